@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxBackfillDays bounds the days of a backfill job.
+const maxBackfillDays = 366
+
+// maxBackfillJobs bounds the jobs kept for progress reporting, the oldest
+// finished jobs are forgotten beyond it.
+const maxBackfillJobs = 20
+
+// BackfillJob is the progress of a backfill started on the admin API.
+type BackfillJob struct {
+	ID        int       `json:"id"`
+	Zone      string    `json:"zone"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Sinks     []string  `json:"sinks"`
+	Days      int       `json:"days"`
+	DaysDone  int       `json:"days_done"`
+	Intervals int       `json:"intervals"`
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished,omitzero"`
+}
+
+// PriceClientState is the in-memory state of a price client.
+type PriceClientState struct {
+	Zone     string    `json:"zone"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Today    Prices    `json:"today"`
+	Tomorrow Prices    `json:"tomorrow"`
+}
+
+func (p *PriceClient) state() PriceClientState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PriceClientState{
+		Zone:     p.priceClass,
+		LoadedAt: p.loadedAt,
+		Today:    append(Prices(nil), p.prices...),
+		Tomorrow: append(Prices(nil), p.tomorrow...),
+	}
+}
+
+// adminAPI serves the /api/v1/admin/ endpoints, which need a token of the
+// admin scope, see authenticator.
+type adminAPI struct {
+	pcs     PriceClients
+	routes  []*sinkRoute
+	history *HistoryStore
+
+	mu     sync.Mutex
+	nextID int
+	jobs   []*BackfillJob
+	// backfilling holds the routes of the running jobs, a route is
+	// backfilled by one job at a time.
+	backfilling map[*sinkRoute]bool
+}
+
+func newAdminAPI(pcs PriceClients, routes []*sinkRoute, history *HistoryStore) *adminAPI {
+	return &adminAPI{pcs: pcs, routes: routes, history: history}
+}
+
+// register adds the admin endpoints to mux.
+func (a *adminAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/admin/reload", a.reload)
+	mux.HandleFunc("GET /api/v1/admin/state", a.state)
+	mux.HandleFunc("GET /api/v1/admin/sinks", a.sinks)
+	mux.HandleFunc("POST /api/v1/admin/sinks/{name}/pause", a.pause(true))
+	mux.HandleFunc("POST /api/v1/admin/sinks/{name}/resume", a.pause(false))
+	mux.HandleFunc("POST /api/v1/admin/backfill", a.startBackfill)
+	mux.HandleFunc("GET /api/v1/admin/backfill", a.backfillJobs)
+	mux.HandleFunc("GET /api/v1/admin/backfill/{id}", a.backfillJobs)
+	mux.HandleFunc("GET /api/v1/admin/loglevel", a.logLevel)
+	mux.HandleFunc("POST /api/v1/admin/loglevel", a.logLevel)
+}
+
+// reload fetches the prices of the zone and date query parameters again,
+// date defaults to today.
+func (a *adminAPI) reload(w http.ResponseWriter, r *http.Request) {
+	pc := zoneClient(w, r, a.pcs)
+	if pc == nil {
+		return
+	}
+	date, _, err := parseDateRange(r.URL.Query().Get("date"), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prices, err := pc.Reload(date)
+	if errors.Is(err, errNotLoadable) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errNotPublished) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	pc.logger().Info("Reloaded prices on admin request", "date", date.Format(time.DateOnly), "intervals", len(prices))
+	writeJSON(w, pc.state())
+}
+
+func (a *adminAPI) state(w http.ResponseWriter, r *http.Request) {
+	states := make([]PriceClientState, 0, len(a.pcs))
+	for _, pc := range a.pcs {
+		states = append(states, pc.state())
+	}
+	writeJSON(w, states)
+}
+
+func (a *adminAPI) sinks(w http.ResponseWriter, r *http.Request) {
+	statuses := make([]RouteStatus, 0, len(a.routes))
+	for _, route := range a.routes {
+		statuses = append(statuses, route.status())
+	}
+	writeJSON(w, statuses)
+}
+
+func (a *adminAPI) route(name string) *sinkRoute {
+	for _, route := range a.routes {
+		if route.name == name {
+			return route
+		}
+	}
+	return nil
+}
+
+// pause pauses or resumes the route of the name path value. Resuming also
+// enables a route disabled by the disable failure policy.
+func (a *adminAPI) pause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := a.route(r.PathValue("name"))
+		if route == nil {
+			http.Error(w, fmt.Sprintf("unknown sink %q", r.PathValue("name")), http.StatusNotFound)
+			return
+		}
+		if paused {
+			route.paused.Store(true)
+		} else {
+			route.resume()
+		}
+		route.logger().Info("Sink paused on admin request", "paused", paused)
+		writeJSON(w, route.status())
+	}
+}
+
+// startBackfill starts a job fetching the days of the zone, from and to
+// query parameters from the API, see parseHistoryRange, into the history
+// store and the sinks of the sink query parameters. Paused or disabled
+// sinks and sinks of a running job are refused.
+func (a *adminAPI) startBackfill(w http.ResponseWriter, r *http.Request) {
+	pc := zoneClient(w, r, a.pcs)
+	if pc == nil {
+		return
+	}
+	q := r.URL.Query()
+	from, to, err := parseHistoryRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var routes []*sinkRoute
+	for _, name := range q["sink"] {
+		route := a.route(name)
+		if route == nil {
+			http.Error(w, fmt.Sprintf("unknown sink %q", name), http.StatusNotFound)
+			return
+		}
+		if route.paused.Load() || route.isDisabled() {
+			http.Error(w, fmt.Sprintf("sink %q is paused or disabled", name), http.StatusConflict)
+			return
+		}
+		if !slices.Contains(routes, route) {
+			routes = append(routes, route)
+		}
+	}
+	if len(routes) == 0 && a.history == nil {
+		http.Error(w, "no sink given and no -history store to backfill", http.StatusBadRequest)
+		return
+	}
+	var days []time.Time
+	year, month, day := from.In(locale).Date()
+	for date := time.Date(year, month, day, 0, 0, 0, 0, locale); date.Before(to); date = date.AddDate(0, 0, 1) {
+		days = append(days, date)
+	}
+	if len(days) > maxBackfillDays {
+		http.Error(w, fmt.Sprintf("range longer than %d days", maxBackfillDays), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	for _, route := range routes {
+		if a.backfilling[route] {
+			a.mu.Unlock()
+			http.Error(w, fmt.Sprintf("sink %q is already being backfilled", route.name), http.StatusConflict)
+			return
+		}
+	}
+	if a.backfilling == nil {
+		a.backfilling = make(map[*sinkRoute]bool)
+	}
+	for _, route := range routes {
+		a.backfilling[route] = true
+	}
+	a.nextID++
+	job := &BackfillJob{ID: a.nextID, Zone: pc.Zone(), From: from, To: to, Sinks: q["sink"], Days: len(days), State: "running", Started: clockSourceNow()}
+	a.jobs = append(a.jobs, job)
+	for i := 0; len(a.jobs) > maxBackfillJobs && i < len(a.jobs); {
+		if a.jobs[i].State == "running" {
+			i++
+			continue
+		}
+		a.jobs = slices.Delete(a.jobs, i, i+1)
+	}
+	res := *job
+	a.mu.Unlock()
+
+	pc.logger().Info("Backfill started", "job", job.ID, "from", from, "to", to)
+	go a.backfill(job, pc, days, routes)
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/backfill/%d", res.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, res)
+}
+
+// backfill runs the job, recording its progress after every day.
+func (a *adminAPI) backfill(job *BackfillJob, pc *PriceClient, days []time.Time, routes []*sinkRoute) {
+	err := func() error {
+		for _, date := range days {
+			prices, err := pc.PricesFor(date)
+			if errors.Is(err, errNotPublished) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("error fetching %s: %v", date.Format(time.DateOnly), err)
+			}
+			var inRange Prices
+			for _, p := range prices {
+				if p.TimeEnd.After(job.From) && p.TimeStart.Before(job.To) {
+					inRange = append(inRange, p)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if a.history != nil {
+				if err := a.history.Save(ctx, pc.Zone(), prices); err != nil {
+					cancel()
+					return fmt.Errorf("error saving history: %v", err)
+				}
+			}
+			for _, route := range routes {
+				if err := route.backfill(ctx, pc.Zone(), inRange); err != nil {
+					cancel()
+					return fmt.Errorf("error writing to %s: %v", route.name, err)
+				}
+			}
+			cancel()
+			a.mu.Lock()
+			job.DaysDone++
+			job.Intervals += len(inRange)
+			a.mu.Unlock()
+		}
+		return nil
+	}()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, route := range routes {
+		delete(a.backfilling, route)
+	}
+	job.State = "done"
+	if err != nil {
+		job.State = "failed"
+		job.Error = err.Error()
+	}
+	job.Finished = clockSourceNow()
+	pc.logger().Info("Backfill finished", "job", job.ID, "state", job.State, "days_done", job.DaysDone, "days", job.Days, "error", job.Error)
+}
+
+// logLevel serves the level of the default logger, set to the level query
+// parameter on POST.
+func (a *adminAPI) logLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(r.URL.Query().Get("level"))); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logLevel.Set(level)
+		slog.Info("Log level changed on admin request", "level", level)
+	}
+	writeJSON(w, map[string]string{"level": logLevel.Level().String()})
+}
+
+// backfillJobs serves the job of the id path value, or all kept jobs.
+func (a *adminAPI) backfillJobs(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id := r.PathValue("id")
+	if id == "" {
+		jobs := make([]BackfillJob, 0, len(a.jobs))
+		for _, job := range a.jobs {
+			jobs = append(jobs, *job)
+		}
+		writeJSON(w, jobs)
+		return
+	}
+	for _, job := range a.jobs {
+		if fmt.Sprint(job.ID) == id {
+			writeJSON(w, *job)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("unknown backfill job %q", id), http.StatusNotFound)
+}
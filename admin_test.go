@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdminAPI(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/prices/2025/02-02_SE3.json":
+			fmt.Fprintln(w, day1)
+		case "/api/v1/prices/2025/02-03_SE3.json":
+			fmt.Fprintln(w, day2)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	pc := &PriceClient{priceClass: "SE3", baseURL: upstream.URL, client: upstream.Client()}
+	sink := &recordSink{}
+	route := newSinkRoute(sink)
+	admin := newAdminAPI(PriceClients{pc}, []*sinkRoute{route}, nil)
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{pc}, nil, admin))
+	defer ts.Close()
+
+	do := func(method, path string, v any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode < 300 {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "reload today", method: http.MethodPost, path: "/api/v1/admin/reload?zone=SE3", want: http.StatusOK},
+		{name: "reload tomorrow", method: http.MethodPost, path: "/api/v1/admin/reload?date=2025-02-03", want: http.StatusOK},
+		{name: "reload past", method: http.MethodPost, path: "/api/v1/admin/reload?date=2025-01-03", want: http.StatusBadRequest},
+		{name: "reload unknown zone", method: http.MethodPost, path: "/api/v1/admin/reload?zone=SE1", want: http.StatusNotFound},
+		{name: "reload with get", method: http.MethodGet, path: "/api/v1/admin/reload", want: http.StatusMethodNotAllowed},
+		{name: "pause unknown sink", method: http.MethodPost, path: "/api/v1/admin/sinks/nope/pause", want: http.StatusNotFound},
+		{name: "backfill without sink", method: http.MethodPost, path: "/api/v1/admin/backfill", want: http.StatusBadRequest},
+		{name: "backfill too long", method: http.MethodPost, path: "/api/v1/admin/backfill?sink=record&from=2020-01-01&to=2025-01-01", want: http.StatusBadRequest},
+		{name: "unknown job", method: http.MethodGet, path: "/api/v1/admin/backfill/42", want: http.StatusNotFound},
+		{name: "log level", method: http.MethodGet, path: "/api/v1/admin/loglevel", want: http.StatusOK},
+		{name: "set log level", method: http.MethodPost, path: "/api/v1/admin/loglevel?level=info", want: http.StatusOK},
+		{name: "invalid log level", method: http.MethodPost, path: "/api/v1/admin/loglevel?level=loud", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := do(tt.method, tt.path, nil); got != tt.want {
+				t.Errorf("status got = %d, want = %d", got, tt.want)
+			}
+		})
+	}
+
+	var states []PriceClientState
+	do(http.MethodGet, "/api/v1/admin/state", &states)
+	if len(states) != 1 || len(states[0].Today) != 24 || len(states[0].Tomorrow) != 24 || !states[0].LoadedAt.Equal(fakec.curtime) {
+		t.Errorf("state got = %+v", states)
+	}
+
+	var status RouteStatus
+	if do(http.MethodPost, "/api/v1/admin/sinks/record/pause", &status); !status.Paused || !route.paused.Load() {
+		t.Errorf("pause got = %+v", status)
+	}
+	if got := do(http.MethodPost, "/api/v1/admin/backfill?sink=record", nil); got != http.StatusConflict {
+		t.Errorf("backfill of paused sink status got = %d, want = %d", got, http.StatusConflict)
+	}
+	if do(http.MethodPost, "/api/v1/admin/sinks/record/resume", &status); status.Paused {
+		t.Errorf("resume got = %+v", status)
+	}
+	// Resuming enables a route disabled by its failure policy.
+	route.onFailure, route.maxFailures = "disable", 1
+	sink.err = errors.New("unavailable")
+	route.write([]Sample{{Zone: "SE3"}})
+	sink.err = nil
+	if !route.status().Disabled {
+		t.Fatalf("route not disabled")
+	}
+	if got := do(http.MethodPost, "/api/v1/admin/backfill?sink=record", nil); got != http.StatusConflict {
+		t.Errorf("backfill of disabled sink status got = %d, want = %d", got, http.StatusConflict)
+	}
+	if do(http.MethodPost, "/api/v1/admin/sinks/record/resume", &status); status.Disabled || status.Failures != 0 {
+		t.Errorf("resume of disabled route got = %+v", status)
+	}
+
+	// A sink is backfilled by one job at a time.
+	admin.mu.Lock()
+	admin.backfilling = map[*sinkRoute]bool{route: true}
+	admin.mu.Unlock()
+	if got := do(http.MethodPost, "/api/v1/admin/backfill?sink=record", nil); got != http.StatusConflict {
+		t.Errorf("second backfill status got = %d, want = %d", got, http.StatusConflict)
+	}
+	admin.mu.Lock()
+	delete(admin.backfilling, route)
+	admin.mu.Unlock()
+
+	var job BackfillJob
+	if got := do(http.MethodPost, "/api/v1/admin/backfill?sink=record&from=2025-02-02T12:00&to=2025-02-04", &job); got != http.StatusAccepted {
+		t.Fatalf("backfill status got = %d, want = %d", got, http.StatusAccepted)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.State == "running" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		do(http.MethodGet, fmt.Sprintf("/api/v1/admin/backfill/%d", job.ID), &job)
+	}
+	if job.State != "done" || job.Days != 2 || job.DaysDone != 2 || job.Intervals != 36 {
+		t.Errorf("backfill job got = %+v", job)
+	}
+	admin.mu.Lock()
+	if admin.backfilling[route] {
+		t.Errorf("route still backfilling after the job finished")
+	}
+	admin.mu.Unlock()
+	var samples int
+	for _, batch := range sink.batches {
+		samples += len(batch)
+	}
+	if samples != 36 || !sink.batches[0][0].Time.Equal(time.Date(2025, 2, 2, 12, 0, 0, 0, locale)) {
+		t.Errorf("backfilled samples got = %d", samples)
+	}
+}
+
+func TestAdminBackfillHistorySink(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	past := hourlyPrices(time.Date(2025, 1, 15, 0, 0, 0, 0, locale), 1, 2, 3, 4)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/prices/2025/01-15_SE3.json" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(past)
+	}))
+	defer upstream.Close()
+	pc := loadedPriceClient(t)
+	pc.baseURL, pc.client = upstream.URL, upstream.Client()
+	store, err := openHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	route := newSinkRoute(&historySink{store: store, pcs: PriceClients{pc}})
+	admin := newAdminAPI(PriceClients{pc}, []*sinkRoute{route}, nil)
+
+	from, to := time.Date(2025, 1, 15, 1, 0, 0, 0, locale), time.Date(2025, 1, 16, 0, 0, 0, 0, locale)
+	job := &BackfillJob{ID: 1, From: from, To: to, State: "running"}
+	admin.backfill(job, pc, []time.Time{time.Date(2025, 1, 15, 0, 0, 0, 0, locale)}, []*sinkRoute{route})
+	if job.State != "done" || job.Intervals != 3 {
+		t.Fatalf("backfill job got = %+v", job)
+	}
+	got, err := store.Range(context.Background(), "SE3", from.Add(-time.Hour), to)
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if len(got) != 3 || got[0].SEKPerkWh != 2 || !got[0].TimeStart.Equal(from) {
+		t.Errorf("backfilled rows got = %+v, want the intervals from 01:00", got)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// maxRangeDays bounds the days of a range request, past days are fetched
+// from the API one request per day and cached, see PricesFor.
+const maxRangeDays = 31
+
+// apiPrice is a price interval in the requested currency and time zone.
+type apiPrice struct {
+	TimeStart time.Time `json:"time_start"`
+	TimeEnd   time.Time `json:"time_end"`
+	Price     float64   `json:"price"`
+}
+
+// apiPrices are the price intervals of a zone.
+type apiPrices struct {
+	Zone     string     `json:"zone"`
+	Currency string     `json:"currency"`
+	Prices   []apiPrice `json:"prices"`
+}
+
+// apiCurrent is the active price interval of a zone.
+type apiCurrent struct {
+	Zone     string `json:"zone"`
+	Currency string `json:"currency"`
+	apiPrice
+	Level string `json:"level"`
+}
+
+// apiStats are the statistics of a zone's day.
+type apiStats struct {
+	Zone      string `json:"zone"`
+	Currency  string `json:"currency"`
+	Date      string `json:"date"`
+	Intervals int    `json:"intervals"`
+	Stats
+}
+
+// priceQuery holds the common query parameters of the price endpoints.
+type priceQuery struct {
+	pc       *PriceClient
+	currency string
+	loc      *time.Location
+}
+
+// parsePriceQuery parses the zone, currency and tz query parameters. A 4xx
+// response is written and false returned for invalid values.
+func parsePriceQuery(w http.ResponseWriter, r *http.Request, pcs PriceClients) (priceQuery, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return priceQuery{}, false
+	}
+	q := priceQuery{currency: "SEK", loc: locale}
+	if q.pc = zoneClient(w, r, pcs); q.pc == nil {
+		return q, false
+	}
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		if !slices.Contains(currencies, currency) {
+			http.Error(w, fmt.Sprintf("currency must be one of %v", currencies), http.StatusBadRequest)
+			return q, false
+		}
+		q.currency = currency
+	}
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid tz: %v", err), http.StatusBadRequest)
+			return q, false
+		}
+		q.loc = loc
+	}
+	return q, true
+}
+
+func (q priceQuery) price(p Price) apiPrice {
+	return apiPrice{TimeStart: p.TimeStart.In(q.loc), TimeEnd: p.TimeEnd.In(q.loc), Price: p.PerkWh(q.currency)}
+}
+
+func (q priceQuery) prices(ps Prices) apiPrices {
+	res := apiPrices{Zone: q.pc.Zone(), Currency: q.currency, Prices: make([]apiPrice, 0, len(ps))}
+	for _, p := range ps {
+		res.Prices = append(res.Prices, q.price(p))
+	}
+	return res
+}
+
+// cacheScope returns the Cache-Control scope of the price responses. They
+// need a token if -tokens is set, so shared caches must not store them.
+func cacheScope() string {
+	if *tokensFile != "" {
+		return "private"
+	}
+	return "public"
+}
+
+// setCacheHeaders lets clients cache the response until the active price
+// interval ends, when every response may change.
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, pc *PriceClient) {
+	now := clockSourceNow()
+	current, err := pc.CurrentPrice(r.Context())
+	if err != nil {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	maxAge := int(math.Ceil(current.TimeEnd.Sub(now).Seconds()))
+	w.Header().Set("Cache-Control", cacheScope()+", max-age="+strconv.Itoa(maxAge))
+	w.Header().Set("Expires", current.TimeEnd.UTC().Format(http.TimeFormat))
+	w.Header().Set("Last-Modified", pc.LoadedAt().UTC().Format(http.TimeFormat))
+}
+
+// currentHandler serves the active price interval.
+func currentHandler(pcs PriceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parsePriceQuery(w, r, pcs)
+		if !ok {
+			return
+		}
+		price, err := q.pc.CurrentPrice(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		setCacheHeaders(w, r, q.pc)
+		writeJSON(w, apiCurrent{Zone: q.pc.Zone(), Currency: q.currency, apiPrice: q.price(price), Level: q.pc.Today().Level(price)})
+	}
+}
+
+// dayHandler serves the prices of today, or tomorrow if tomorrow is set.
+func dayHandler(pcs PriceClients, tomorrow bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parsePriceQuery(w, r, pcs)
+		if !ok {
+			return
+		}
+		date := clockSourceNow()
+		if tomorrow {
+			date = date.In(locale).AddDate(0, 0, 1)
+		}
+		prices, err := q.pc.PricesFor(date)
+		if errors.Is(err, errNotPublished) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		setCacheHeaders(w, r, q.pc)
+		writeJSON(w, q.prices(prices))
+	}
+}
+
+// rangeHandler serves the prices of the local days from the from date to
+// the to date, both included and defaulting to today.
+func rangeHandler(pcs PriceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parsePriceQuery(w, r, pcs)
+		if !ok {
+			return
+		}
+		from, to, err := parseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var prices Prices
+		for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+			day, err := q.pc.PricesFor(date)
+			if errors.Is(err, errNotPublished) {
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			prices = append(prices, day...)
+		}
+		setCacheHeaders(w, r, q.pc)
+		writeJSON(w, q.prices(prices))
+	}
+}
+
+// statsHandler serves the statistics of the date query parameter,
+// defaulting to today.
+func statsHandler(pcs PriceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parsePriceQuery(w, r, pcs)
+		if !ok {
+			return
+		}
+		date, _, err := parseDateRange(r.URL.Query().Get("date"), "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prices, err := q.pc.PricesFor(date)
+		if errors.Is(err, errNotPublished) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		setCacheHeaders(w, r, q.pc)
+		writeJSON(w, apiStats{
+			Zone:      q.pc.Zone(),
+			Currency:  q.currency,
+			Date:      date.Format(time.DateOnly),
+			Intervals: len(prices),
+			Stats:     prices.Stats(q.currency),
+		})
+	}
+}
+
+// parseDateRange parses two local 2006-01-02 dates, from defaulting to
+// today and to defaulting to from.
+func parseDateRange(fromStr, toStr string) (from, to time.Time, err error) {
+	year, month, day := clockSourceNow().In(locale).Date()
+	from = time.Date(year, month, day, 0, 0, 0, 0, locale)
+	if fromStr != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, fromStr, locale); err != nil {
+			return from, to, fmt.Errorf("invalid date %q, want 2006-01-02", fromStr)
+		}
+	}
+	to = from
+	if toStr != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, toStr, locale); err != nil {
+			return from, to, fmt.Errorf("invalid date %q, want 2006-01-02", toStr)
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("to %s is before from %s", toStr, fromStr)
+	}
+	if to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("range longer than %d days", maxRangeDays)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPriceAPI(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	// Days other than today and tomorrow are fetched, day1 is served as
+	// 2025-02-01 with its dates unchanged for brevity.
+	var fetches atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path != "/api/v1/prices/2025/02-01_SE3.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, day1)
+	}))
+	defer upstream.Close()
+	pc := loadedPriceClient(t)
+	pc.baseURL = upstream.URL
+	pc.client = upstream.Client()
+	pc.loadedAt = time.Date(2025, 2, 2, 0, 0, 1, 0, locale)
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{pc}, nil, nil))
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "current",
+			path:       "/api/v1/prices/current",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"zone":"SE3","currency":"SEK","time_start":"2025-02-02T10:00:00+01:00","time_end":"2025-02-02T11:00:00+01:00","price":0.78352,"level":"normal"}`,
+		},
+		{
+			name:       "current in EUR and UTC",
+			path:       "/api/v1/prices/current?currency=EUR&tz=UTC",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"zone":"SE3","currency":"EUR","time_start":"2025-02-02T09:00:00Z","time_end":"2025-02-02T10:00:00Z","price":0.06814,"level":"normal"}`,
+		},
+		{
+			name:       "stats",
+			path:       "/api/v1/prices/stats?date=2025-02-03",
+			wantStatus: http.StatusOK,
+			wantBody:   `"date":"2025-02-03","intervals":24,"min":0.40418,"max":2.47038`,
+		},
+		{name: "unknown zone", path: "/api/v1/prices/today?zone=SE1", wantStatus: http.StatusNotFound},
+		{name: "unknown currency", path: "/api/v1/prices/today?currency=USD", wantStatus: http.StatusBadRequest},
+		{name: "unknown tz", path: "/api/v1/prices/today?tz=Mars/Olympus", wantStatus: http.StatusBadRequest},
+		{name: "reversed range", path: "/api/v1/prices/range?from=2025-02-02&to=2025-02-01", wantStatus: http.StatusBadRequest},
+		{name: "unpublished stats", path: "/api/v1/prices/stats?date=2025-02-05", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status got = %d, want = %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body got = %s, want to contain %s", body, tt.wantBody)
+			}
+		})
+	}
+
+	days := []struct {
+		path      string
+		wantCount int
+		wantFirst string
+	}{
+		{path: "/api/v1/prices/today", wantCount: 24, wantFirst: "2025-02-02T00:00:00+01:00"},
+		{path: "/api/v1/prices/tomorrow", wantCount: 24, wantFirst: "2025-02-03T00:00:00+01:00"},
+		{path: "/api/v1/prices/range?from=2025-02-01&to=2025-02-05", wantCount: 72, wantFirst: "2025-02-02T00:00:00+01:00"},
+		{path: "/api/v1/prices/range?from=2025-02-01&to=2025-02-02", wantCount: 48, wantFirst: "2025-02-02T00:00:00+01:00"},
+	}
+	for _, tt := range days {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if got, want := resp.Header.Get("Cache-Control"), "public, max-age=1800"; got != want {
+				t.Errorf("Cache-Control got = %q, want = %q", got, want)
+			}
+			if got, want := resp.Header.Get("Expires"), "Sun, 02 Feb 2025 10:00:00 GMT"; got != want {
+				t.Errorf("Expires got = %q, want = %q", got, want)
+			}
+			var prices apiPrices
+			if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+				t.Fatal(err)
+			}
+			if len(prices.Prices) != tt.wantCount {
+				t.Fatalf("prices got = %d, want = %d", len(prices.Prices), tt.wantCount)
+			}
+			if diff := cmp.Diff(tt.wantFirst, prices.Prices[0].TimeStart.Format(time.RFC3339)); diff != "" {
+				t.Errorf("first interval mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	// Past days are fetched once, later days not at all.
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("upstream fetches got = %d, want = 1", got)
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	defer func(tokens string) { *tokensFile = tokens }(*tokensFile)
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	pc := loadedPriceClient(t)
+	for tokens, want := range map[string]string{"": "public, max-age=1800", "tokens.json": "private, max-age=1800"} {
+		*tokensFile = tokens
+		w := httptest.NewRecorder()
+		setCacheHeaders(w, httptest.NewRequest(http.MethodGet, "/api/v1/prices/current", nil), pc)
+		if got := w.Header().Get("Cache-Control"); got != want {
+			t.Errorf("Cache-Control with tokens %q got = %q, want = %q", tokens, got, want)
+		}
+	}
+}
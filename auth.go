@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var tlsCert = flag.String("tls-cert", "", "TLS certificate file for the HTTP, metrics and gRPC listeners, reloaded on change, plain text if empty")
+var tlsKey = flag.String("tls-key", "", "TLS private key file")
+var tlsClientCA = flag.String("tls-client-ca", "", "CA file client certificates must be signed by, client certificates are not verified if empty")
+var tokensFile = flag.String("tokens", "", "JSON file with the bearer tokens accepted by the listeners, no authentication if empty")
+var rateLimit = flag.Float64("ratelimit", 0, "Requests per second allowed per client, unlimited if 0")
+var rateLimitBurst = flag.Int("ratelimit-burst", 20, "Requests a client may burst above -ratelimit")
+
+const (
+	scopeRead  = "read"
+	scopeAdmin = "admin"
+)
+
+// Token is an entry of the -tokens file. Admin tokens may also read.
+type Token struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Scope string `json:"scope"`
+}
+
+// certReloader serves the certificate of the cert and key files, reloading
+// them when either file changes.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var modTime time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			if c.cert != nil {
+				// Keep serving the loaded certificate while files are replaced.
+				return c.cert, nil
+			}
+			return nil, err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if c.cert != nil && modTime.Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			slog.Error("Reloading TLS certificate failed, keeping the previous one", "cert", c.certFile, "error", err)
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil {
+		slog.Info("Reloaded TLS certificate", "cert", c.certFile)
+	}
+	c.cert, c.modTime = &cert, modTime
+	return c.cert, nil
+}
+
+// newTLSConfig returns the TLS config of the cert and key files, requiring
+// client certificates signed by clientCA if set.
+func newTLSConfig(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return nil, fmt.Errorf("error loading TLS certificate: %v", err)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.GetCertificate}
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", clientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// bucket is the token bucket of a client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the requests of each client with a token bucket.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(max(burst, 1)), clients: map[string]*bucket{}}
+}
+
+// allow reports whether the client may make a request now, and otherwise
+// how long until it may.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Forget clients whose bucket has refilled.
+	if now.Sub(l.lastSweep) > time.Minute {
+		for c, b := range l.clients {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.clients, c)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.clients[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// authenticator checks the bearer tokens and rate limits of the listeners.
+// Without tokens every request is allowed, and without a rate every client
+// is unlimited.
+type authenticator struct {
+	tokens  []Token
+	limiter *rateLimiter
+}
+
+// newAuthenticator returns the authenticator of the -tokens and -ratelimit
+// flags.
+func newAuthenticator() (*authenticator, error) {
+	a := &authenticator{}
+	if *tokensFile != "" {
+		b, err := os.ReadFile(*tokensFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &a.tokens); err != nil {
+			return nil, fmt.Errorf("error parsing tokens file %s: %v", *tokensFile, err)
+		}
+		for _, t := range a.tokens {
+			if t.Token == "" || (t.Scope != scopeRead && t.Scope != scopeAdmin) {
+				return nil, fmt.Errorf("token %q must have a token and a read or admin scope", t.Name)
+			}
+		}
+	}
+	if *rateLimit > 0 {
+		a.limiter = newRateLimiter(*rateLimit, *rateLimitBurst)
+	}
+	return a, nil
+}
+
+// authorize returns the name of the token in the authorization header
+// value, or the code of the error if it is missing, unknown or lacks scope.
+// No token is needed for the empty scope.
+func (a *authenticator) authorize(authorization, scope string) (string, codes.Code) {
+	if len(a.tokens) == 0 || scope == "" {
+		return "", codes.OK
+	}
+	bearer, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return "", codes.Unauthenticated
+	}
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(t.Token)) == 1 {
+			if scope == scopeAdmin && t.Scope != scopeAdmin {
+				return t.Name, codes.PermissionDenied
+			}
+			return t.Name, codes.OK
+		}
+	}
+	return "", codes.Unauthenticated
+}
+
+// requiredScope returns the scope needed for the request, none for the
+// liveness and readiness probes, admin for the /api/v1/admin/ endpoints and
+// read otherwise.
+func requiredScope(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/healthz" || r.URL.Path == "/readyz":
+		return ""
+	case strings.HasPrefix(r.URL.Path, "/api/v1/admin/"):
+		return scopeAdmin
+	}
+	return scopeRead
+}
+
+// clientID identifies the client for rate limiting, by token name if
+// authenticated and by address otherwise. Failed attempts count against
+// the address, so guessing tokens is rate limited too.
+func clientID(name, addr string) string {
+	if name != "" {
+		return "token:" + name
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// wrap authenticates and rate limits the requests to h.
+func (a *authenticator) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, code := a.authorize(r.Header.Get("Authorization"), requiredScope(r))
+		if a.limiter != nil {
+			if ok, wait := a.limiter.allow(clientID(name, r.RemoteAddr), time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+		switch code {
+		case codes.Unauthenticated:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="price2influx"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		case codes.PermissionDenied:
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// check authenticates and rate limits a gRPC call, all methods need the
+// read scope.
+func (a *authenticator) check(ctx context.Context) error {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		authorization = md.Get("authorization")[0]
+	}
+	name, code := a.authorize(authorization, scopeRead)
+	if a.limiter != nil {
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		if ok, _ := a.limiter.allow(clientID(name, addr), time.Now()); !ok {
+			return status.Error(codes.ResourceExhausted, "too many requests")
+		}
+	}
+	if code != codes.OK {
+		return status.Error(code, strings.ToLower(code.String()))
+	}
+	return nil
+}
+
+// grpcOptions returns the server options enforcing the authenticator.
+func (a *authenticator) grpcOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := a.check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := a.check(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// untracedPaths are the scraped and probed endpoints, not traced as they
+// would flood the traces.
+var untracedPaths = []string{"/metrics", "/healthz", "/readyz", "/status"}
+
+// tracedHandler traces the requests to h but those to the untracedPaths.
+func tracedHandler(h http.Handler) http.Handler {
+	return otelhttp.NewHandler(h, "price2influx", otelhttp.WithFilter(func(r *http.Request) bool {
+		return !slices.Contains(untracedPaths, r.URL.Path)
+	}))
+}
+
+// listenAndServe serves h on addr behind the authenticator, with TLS if
+// tlsConfig is set, until ctx is done. Requests are traced, see
+// tracedHandler.
+func listenAndServe(ctx context.Context, addr string, h http.Handler, a *authenticator, tlsConfig *tls.Config) error {
+	srv := &http.Server{Addr: addr, Handler: tracedHandler(a.wrap(h)), TLSConfig: tlsConfig}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	var err error
+	if tlsConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
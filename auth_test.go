@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Date(2025, 2, 2, 10, 0, 0, 0, locale)
+	l := newRateLimiter(1, 2)
+	tests := []struct {
+		name     string
+		client   string
+		at       time.Duration
+		want     bool
+		wantWait time.Duration
+	}{
+		{name: "burst 1", client: "esp", want: true},
+		{name: "burst 2", client: "esp", want: true},
+		{name: "exhausted", client: "esp", want: false, wantWait: time.Second},
+		{name: "other client", client: "other", want: true},
+		{name: "half refilled", client: "esp", at: 500 * time.Millisecond, want: false, wantWait: 500 * time.Millisecond},
+		{name: "refilled", client: "esp", at: time.Second, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, wait := l.allow(tt.client, start.Add(tt.at))
+			if got != tt.want || wait != tt.wantWait {
+				t.Errorf("allow() got = %v, %v, want = %v, %v", got, wait, tt.want, tt.wantWait)
+			}
+		})
+	}
+	l.allow("esp", start.Add(2*time.Minute))
+	if len(l.clients) != 1 {
+		t.Errorf("clients after sweep got = %d, want = 1", len(l.clients))
+	}
+}
+
+func TestAuthenticatorWrap(t *testing.T) {
+	a := &authenticator{
+		tokens: []Token{
+			{Name: "grafana", Token: "read-token", Scope: scopeRead},
+			{Name: "ops", Token: "admin-token", Scope: scopeAdmin},
+			{Name: "viewer", Token: "view-token", Scope: scopeRead},
+		},
+		limiter: newRateLimiter(0.001, 1),
+	}
+	h := a.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name          string
+		path          string
+		addr          string
+		authorization string
+		want          int
+	}{
+		{name: "missing", path: "/api/v1/prices/current", addr: "192.0.2.1:1234", want: http.StatusUnauthorized},
+		{name: "unknown", path: "/api/v1/prices/current", addr: "192.0.2.2:1234", authorization: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "unknown limited", path: "/api/v1/prices/current", addr: "192.0.2.2:5678", authorization: "Bearer guess", want: http.StatusTooManyRequests},
+		{name: "not bearer", path: "/api/v1/prices/current", addr: "192.0.2.3:1234", authorization: "Basic read-token", want: http.StatusUnauthorized},
+		{name: "read", path: "/api/v1/prices/current", addr: "192.0.2.2:1234", authorization: "Bearer read-token", want: http.StatusOK},
+		{name: "read limited", path: "/api/v1/prices/today", addr: "192.0.2.4:1234", authorization: "Bearer read-token", want: http.StatusTooManyRequests},
+		{name: "read on admin", path: "/api/v1/admin/state", addr: "192.0.2.4:1234", authorization: "Bearer view-token", want: http.StatusForbidden},
+		{name: "admin", path: "/api/v1/admin/state", addr: "192.0.2.1:1234", authorization: "Bearer admin-token", want: http.StatusOK},
+		{name: "probe", path: "/readyz", addr: "192.0.2.5:1234", want: http.StatusOK},
+		{name: "status", path: "/status", addr: "192.0.2.6:1234", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.addr
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status got = %d, want = %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	if _, code := (&authenticator{}).authorize("", scopeAdmin); code != codes.OK {
+		t.Errorf("authorize() without tokens got = %v, want = OK", code)
+	}
+}
+
+// writeTestCert writes a self-signed certificate for the common name to
+// cert.pem and key.pem in dir.
+func writeTestCert(t *testing.T, dir, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if _, err := newTLSConfig(certFile, keyFile, ""); err == nil {
+		t.Fatal("newTLSConfig() without files error = nil")
+	}
+	writeTestCert(t, dir, "first.example")
+	config, err := newTLSConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("newTLSConfig() error = %v", err)
+	}
+	commonName := func() string {
+		cert, err := config.GetCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if got := commonName(); got != "first.example" {
+		t.Errorf("certificate got = %s, want = first.example", got)
+	}
+
+	writeTestCert(t, dir, "second.example")
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := commonName(); got != "second.example" {
+		t.Errorf("reloaded certificate got = %s, want = second.example", got)
+	}
+
+	// A broken replacement keeps the loaded certificate.
+	if err := os.WriteFile(keyFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(keyFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(); got != "second.example" {
+		t.Errorf("certificate after broken key got = %s, want = second.example", got)
+	}
+
+	writeTestCert(t, dir, "ca.example")
+	config, err = newTLSConfig(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("newTLSConfig() with client CA error = %v", err)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Errorf("ClientAuth got = %v, want = RequireAndVerifyClientCert", config.ClientAuth)
+	}
+	if _, err := newTLSConfig(certFile, keyFile, keyFile); err == nil {
+		t.Errorf("newTLSConfig() with key as client CA error = nil")
+	}
+}
+
+func TestListenAndServeShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- listenAndServe(ctx, "127.0.0.1:0", http.NotFoundHandler(), &authenticator{}, nil)
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("listenAndServe() error = %v, want = nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listenAndServe() did not return after ctx was done")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+var batteryCapacity = flag.Float64("battery-capacity", 0, "Battery capacity in kWh, the battery optimizer is disabled if 0")
+var batteryChargePower = flag.Float64("battery-charge-power", 3, "Battery max charge power in kW drawn from the grid")
+var batteryDischargePower = flag.Float64("battery-discharge-power", 3, "Battery max discharge power in kW delivered to the grid")
+var batteryEfficiency = flag.Float64("battery-efficiency", 0.9, "Battery round-trip efficiency, between 0 and 1")
+var batterySoC = flag.Float64("battery-soc", 0, "Battery initial state of charge in kWh")
+var batterySoCMeasurement = flag.String("battery-soc-measurement", "", "InfluxDB measurement with the battery state of charge in kWh, the planner otherwise assumes the previous plan was followed")
+var batterySoCField = flag.String("battery-soc-field", "soc_kwh", "InfluxDB field of -battery-soc-measurement holding the state of charge")
+var batteryPlanInterval = flag.Duration("battery-planinterval", time.Hour, "How often the battery plan is recomputed and written to InfluxDB")
+
+// batteryLevels is the number of state of charge steps the optimizer uses.
+const batteryLevels = 100
+
+type BatteryAction string
+
+const (
+	BatteryCharge    BatteryAction = "charge"
+	BatteryIdle      BatteryAction = "idle"
+	BatteryDischarge BatteryAction = "discharge"
+)
+
+// Battery describes a home battery, energies are in kWh and powers in kW.
+type Battery struct {
+	CapacityKWh   float64 `json:"capacity_kwh"`
+	ChargeKW      float64 `json:"charge_kw"`
+	DischargeKW   float64 `json:"discharge_kw"`
+	Efficiency    float64 `json:"efficiency"`
+	InitialSoCKWh float64 `json:"initial_soc_kwh"`
+}
+
+// BatteryStep is the planned action for a single price interval. GridKWh is
+// the energy drawn from the grid, negative when discharging.
+type BatteryStep struct {
+	TimeStart time.Time     `json:"time_start"`
+	TimeEnd   time.Time     `json:"time_end"`
+	Action    BatteryAction `json:"action"`
+	GridKWh   float64       `json:"grid_kwh"`
+	SoCKWh    float64       `json:"soc_kwh"`
+	SEKPerkWh float64       `json:"SEK_per_kWh"`
+}
+
+// BatteryPlan is a charge schedule and its expected savings in SEK compared
+// to leaving the battery idle.
+type BatteryPlan struct {
+	Steps   []BatteryStep `json:"steps"`
+	Savings float64       `json:"savings"`
+}
+
+func batteryFromFlags() Battery {
+	return Battery{
+		CapacityKWh:   *batteryCapacity,
+		ChargeKW:      *batteryChargePower,
+		DischargeKW:   *batteryDischargePower,
+		Efficiency:    *batteryEfficiency,
+		InitialSoCKWh: *batterySoC,
+	}
+}
+
+func (b Battery) validate() error {
+	for _, v := range []float64{b.CapacityKWh, b.ChargeKW, b.DischargeKW, b.Efficiency, b.InitialSoCKWh} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("battery parameters must be finite")
+		}
+	}
+	switch {
+	case b.CapacityKWh <= 0:
+		return fmt.Errorf("battery capacity must be positive")
+	case b.ChargeKW < 0 || b.DischargeKW < 0:
+		return fmt.Errorf("battery power limits must not be negative")
+	case b.Efficiency <= 0 || b.Efficiency > 1:
+		return fmt.Errorf("battery efficiency must be in (0, 1]")
+	case b.InitialSoCKWh < 0 || b.InitialSoCKWh > b.CapacityKWh:
+		return fmt.Errorf("battery state of charge must be between 0 and the capacity")
+	}
+	return nil
+}
+
+// OptimizeBattery plans the battery for all loaded intervals that have not ended at from.
+func (p *PriceClient) OptimizeBattery(b Battery, from time.Time) (*BatteryPlan, error) {
+	var prices Prices
+	for _, price := range p.Schedule(context.Background()) {
+		if price.TimeEnd.After(from) {
+			prices = append(prices, price)
+		}
+	}
+	return optimizeBattery(prices, b)
+}
+
+// optimizeBattery runs a dynamic program over the intervals with the state
+// of charge discretized into batteryLevels steps. The round-trip losses are
+// split evenly between charging and discharging.
+func optimizeBattery(prices Prices, b Battery) (*BatteryPlan, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	if len(prices) == 0 {
+		return nil, errNoPrices
+	}
+	eta := math.Sqrt(b.Efficiency)
+	step := b.CapacityKWh / batteryLevels
+	grid := func(ds int) float64 {
+		if ds > 0 {
+			return float64(ds) * step / eta
+		}
+		return float64(ds) * step * eta
+	}
+
+	// value[t][s] is the best revenue from interval t onwards starting at level s.
+	value := make([][]float64, len(prices)+1)
+	choice := make([][]int, len(prices))
+	value[len(prices)] = make([]float64, batteryLevels+1)
+	for t := len(prices) - 1; t >= 0; t-- {
+		hours := prices[t].TimeEnd.Sub(prices[t].TimeStart).Hours()
+		maxUp := int(math.Floor(b.ChargeKW*hours*eta/step + 1e-9))
+		maxDown := int(math.Floor(b.DischargeKW*hours/eta/step + 1e-9))
+		value[t] = make([]float64, batteryLevels+1)
+		choice[t] = make([]int, batteryLevels+1)
+		for s := 0; s <= batteryLevels; s++ {
+			best, bestDS := value[t+1][s], 0
+			for ds := -min(maxDown, s); ds <= min(maxUp, batteryLevels-s); ds++ {
+				v := value[t+1][s+ds] - prices[t].SEKPerkWh*grid(ds)
+				if v > best+1e-12 {
+					best, bestDS = v, ds
+				}
+			}
+			value[t][s], choice[t][s] = best, bestDS
+		}
+	}
+
+	s := int(math.Round(b.InitialSoCKWh / step))
+	plan := &BatteryPlan{Savings: value[0][s]}
+	for t, price := range prices {
+		ds := choice[t][s]
+		s += ds
+		action := BatteryIdle
+		if ds > 0 {
+			action = BatteryCharge
+		} else if ds < 0 {
+			action = BatteryDischarge
+		}
+		plan.Steps = append(plan.Steps, BatteryStep{
+			TimeStart: price.TimeStart,
+			TimeEnd:   price.TimeEnd,
+			Action:    action,
+			GridKWh:   grid(ds),
+			SoCKWh:    float64(s) * step,
+			SEKPerkWh: price.SEKPerkWh,
+		})
+	}
+	return plan, nil
+}
+
+// writeBatteryPlan writes the plan as a forecast series timestamped at the
+// start of each interval.
+func writeBatteryPlan(ctx context.Context, writeAPI api.WriteAPIBlocking, plan *BatteryPlan) error {
+	for _, step := range plan.Steps {
+		p := influxdb2.NewPointWithMeasurement("battery_plan").
+			AddTag("currency", "SEK").
+			AddField("action", string(step.Action)).
+			AddField("grid_kwh", step.GridKWh).
+			AddField("soc_kwh", step.SoCKWh).
+			AddField("price", step.SEKPerkWh).
+			AddField("savings", plan.Savings).
+			SetTime(step.TimeStart)
+		if err := writeAPI.WritePoint(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plannedSoC returns the state of charge at t if the battery follows the
+// plan from the initial state of charge, interpolating within a step.
+func plannedSoC(plan *BatteryPlan, initial float64, t time.Time) float64 {
+	soc := initial
+	for _, step := range plan.Steps {
+		if !t.After(step.TimeStart) {
+			return soc
+		}
+		if t.Before(step.TimeEnd) {
+			done := float64(t.Sub(step.TimeStart)) / float64(step.TimeEnd.Sub(step.TimeStart))
+			return soc + (step.SoCKWh-soc)*done
+		}
+		soc = step.SoCKWh
+	}
+	return soc
+}
+
+// querySoC returns the last state of charge sample of the measurement.
+func querySoC(ctx context.Context, queryAPI api.QueryAPI, bucket, measurement, field string) (float64, error) {
+	query := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: -1d)
+  |> filter(fn: (r) => r._measurement == %q and r._field == %q)
+  |> last()`,
+		bucket, measurement, field)
+	result, err := queryAPI.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer result.Close()
+	for result.Next() {
+		if soc, ok := result.Record().Value().(float64); ok {
+			return soc, nil
+		}
+	}
+	if err := result.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no %s samples of %s in the last day", field, measurement)
+}
+
+// BatteryPlanner recomputes the battery plan and writes it to InfluxDB every
+// -battery-planinterval. The first plan starts from -battery-soc, later plans
+// from the -battery-soc-measurement, or the state of charge the previous
+// plan reached if it is not set or cannot be read.
+func (p *PriceClient) BatteryPlanner(ctx context.Context, queryAPI api.QueryAPI, writeAPI api.WriteAPIBlocking) {
+	soc := *batterySoC
+	var last *BatteryPlan
+	lastSoC := soc
+	for {
+		now := clockSourceNow()
+		reqCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if last != nil {
+			soc = plannedSoC(last, lastSoC, now)
+		}
+		if *batterySoCMeasurement != "" {
+			measured, err := querySoC(reqCtx, queryAPI, *influxBucket, *batterySoCMeasurement, *batterySoCField)
+			if err != nil {
+				p.logger().Warn("Reading battery state of charge failed, using the planned state", "error", err)
+			} else {
+				soc = measured
+			}
+		}
+		b := batteryFromFlags()
+		b.InitialSoCKWh = min(max(soc, 0), b.CapacityKWh)
+		plan, err := p.OptimizeBattery(b, now)
+		if err != nil {
+			p.logger().Warn("OptimizeBattery failed", "error", err)
+		} else {
+			last, lastSoC = plan, b.InitialSoCKWh
+			err = writeBatteryPlan(reqCtx, writeAPI, plan)
+			if err != nil {
+				p.logger().Error("Write battery plan to influx failed", "error", err)
+			}
+		}
+		cancel()
+		if !sleep(ctx, *batteryPlanInterval) {
+			return
+		}
+	}
+}
+
+// batteryHandler serves the battery plan, the flag values can be overridden
+// with the capacity, charge_power, discharge_power, efficiency and soc query
+// parameters and the priceclass with zone.
+func batteryHandler(pcs PriceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		pc := zoneClient(w, r, pcs)
+		if pc == nil {
+			return
+		}
+		b := batteryFromFlags()
+		q := r.URL.Query()
+		for name, v := range map[string]*float64{
+			"capacity":        &b.CapacityKWh,
+			"charge_power":    &b.ChargeKW,
+			"discharge_power": &b.DischargeKW,
+			"efficiency":      &b.Efficiency,
+			"soc":             &b.InitialSoCKWh,
+		} {
+			if !q.Has(name) {
+				continue
+			}
+			f, err := strconv.ParseFloat(q.Get(name), 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %v", name, err), http.StatusBadRequest)
+				return
+			}
+			*v = f
+		}
+		plan, err := pc.OptimizeBattery(b, clockSourceNow())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeJSON(w, plan)
+	}
+}
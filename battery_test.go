@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func hourlyPrices(start time.Time, sek ...float64) Prices {
+	var prices Prices
+	for i, v := range sek {
+		prices = append(prices, Price{
+			SEKPerkWh: v,
+			TimeStart: start.Add(time.Duration(i) * time.Hour),
+			TimeEnd:   start.Add(time.Duration(i+1) * time.Hour),
+		})
+	}
+	return prices
+}
+
+func TestOptimizeBattery(t *testing.T) {
+	loc, err := time.LoadLocation(Locale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 2, 2, 0, 0, 0, 0, loc)
+	actions := func(plan *BatteryPlan) []BatteryAction {
+		var got []BatteryAction
+		for _, step := range plan.Steps {
+			got = append(got, step.Action)
+		}
+		return got
+	}
+	tests := []struct {
+		name        string
+		prices      Prices
+		battery     Battery
+		wantErr     bool
+		wantActions []BatteryAction
+		wantSavings float64
+	}{
+		{
+			name:        "lossless arbitrage",
+			prices:      hourlyPrices(start, 1, 3, 1, 3),
+			battery:     Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: 1},
+			wantActions: []BatteryAction{BatteryCharge, BatteryDischarge, BatteryCharge, BatteryDischarge},
+			wantSavings: 4,
+		},
+		{
+			name:        "losses make small spreads unprofitable",
+			prices:      hourlyPrices(start, 1, 1.1, 1, 3),
+			battery:     Battery{CapacityKWh: 0.9, ChargeKW: 1, DischargeKW: 1, Efficiency: 0.81},
+			wantActions: []BatteryAction{BatteryIdle, BatteryIdle, BatteryCharge, BatteryDischarge},
+			wantSavings: 3*0.81 - 1,
+		},
+		{
+			name:        "initial charge is sold at the peak",
+			prices:      hourlyPrices(start, 2, 5, 2),
+			battery:     Battery{CapacityKWh: 2, ChargeKW: 0, DischargeKW: 2, Efficiency: 1, InitialSoCKWh: 2},
+			wantActions: []BatteryAction{BatteryIdle, BatteryDischarge, BatteryIdle},
+			wantSavings: 10,
+		},
+		{
+			name:        "flat prices",
+			prices:      hourlyPrices(start, 1, 1, 1),
+			battery:     Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: 0.9},
+			wantActions: []BatteryAction{BatteryIdle, BatteryIdle, BatteryIdle},
+		},
+		{
+			name:    "invalid efficiency",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "NaN state of charge",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: 1, InitialSoCKWh: math.NaN()},
+			wantErr: true,
+		},
+		{
+			name:    "NaN efficiency",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: math.NaN()},
+			wantErr: true,
+		},
+		{
+			name:    "NaN capacity",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: math.NaN(), ChargeKW: 1, DischargeKW: 1, Efficiency: 1},
+			wantErr: true,
+		},
+		{
+			name:    "infinite capacity",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: math.Inf(1), ChargeKW: 1, DischargeKW: 1, Efficiency: 1},
+			wantErr: true,
+		},
+		{
+			name:    "infinite charge power",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: 1, ChargeKW: math.Inf(1), DischargeKW: 1, Efficiency: 1},
+			wantErr: true,
+		},
+		{
+			name:    "negative infinite state of charge",
+			prices:  hourlyPrices(start, 1),
+			battery: Battery{CapacityKWh: 1, ChargeKW: 1, DischargeKW: 1, Efficiency: 1, InitialSoCKWh: math.Inf(-1)},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := optimizeBattery(tc.prices, tc.battery)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("optimizeBattery() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantActions, actions(plan)); diff != "" {
+				t.Errorf("optimizeBattery() actions mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantSavings, plan.Savings, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("optimizeBattery() savings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlannedSoC(t *testing.T) {
+	start := time.Date(2025, 2, 2, 0, 0, 0, 0, locale)
+	plan := &BatteryPlan{Steps: []BatteryStep{
+		{TimeStart: start, TimeEnd: start.Add(time.Hour), SoCKWh: 2},
+		{TimeStart: start.Add(time.Hour), TimeEnd: start.Add(2 * time.Hour), SoCKWh: 2},
+		{TimeStart: start.Add(2 * time.Hour), TimeEnd: start.Add(3 * time.Hour), SoCKWh: 0},
+	}}
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{at: -time.Hour, want: 1},
+		{at: 0, want: 1},
+		{at: 30 * time.Minute, want: 1.5},
+		{at: 90 * time.Minute, want: 2},
+		{at: 165 * time.Minute, want: 0.5},
+		{at: 4 * time.Hour, want: 0},
+	}
+	for _, tc := range tests {
+		if got := plannedSoC(plan, 1, start.Add(tc.at)); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("plannedSoC(%s) got = %v, want = %v", tc.at, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+var capacityCSV = flag.String("capacity-csv", "", "CSV file with hourly consumption as time,kWh rows for capacity tariff tracking")
+var capacityMeasurement = flag.String("capacity-measurement", "", "InfluxDB measurement with consumption samples in kWh for capacity tariff tracking")
+var capacityField = flag.String("capacity-field", "kwh", "InfluxDB field of -capacity-measurement holding the consumption samples")
+var capacityPeaks = flag.Int("capacity-peaks", 3, "Number of monthly peak hours averaged by the capacity tariff")
+var capacityDaily = flag.Bool("capacity-daily", true, "Only count the highest hour of each day towards the monthly peaks")
+var capacityRate = flag.Float64("capacity-rate", 0, "Capacity tariff in SEK per kW and month")
+var capacityInterval = flag.Duration("capacity-updaterate", time.Minute, "Capacity tariff tracking update rate")
+
+// Consumption is the energy consumed during the hour starting at Hour.
+type Consumption struct {
+	Hour time.Time `json:"hour"`
+	KWh  float64   `json:"kwh"`
+}
+
+// CapacityTariff is a power tariff charging Rate SEK per kW for the average
+// of the Peaks highest hours of the month, taking only the highest hour of
+// each day if Daily is set.
+type CapacityTariff struct {
+	Peaks int
+	Daily bool
+	Rate  float64
+}
+
+// CapacityStatus is the state of the capacity tariff for the current month.
+// Headroom is how many kWh can still be consumed in the current hour
+// without raising the monthly charge.
+type CapacityStatus struct {
+	Peaks          []Consumption `json:"peaks"`
+	PeakAverage    float64       `json:"peak_average"`
+	Charge         float64       `json:"charge"`
+	CurrentHourKWh float64       `json:"current_hour_kwh"`
+	Headroom       float64       `json:"headroom"`
+}
+
+// Status computes the capacity tariff for the month of now, using the hours
+// before the current hour as completed hours and the current hour as
+// consumption so far.
+func (c CapacityTariff) Status(hours []Consumption, now time.Time) CapacityStatus {
+	now = now.In(locale)
+	year, month, day := now.Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, locale)
+	currentHour := now.Truncate(time.Hour)
+
+	var status CapacityStatus
+	var completed []Consumption
+	today := -1.0
+	for _, h := range hours {
+		switch {
+		case h.Hour.Before(monthStart) || h.Hour.After(currentHour):
+			continue
+		case h.Hour.Equal(currentHour):
+			status.CurrentHourKWh += h.KWh
+			continue
+		}
+		completed = append(completed, h)
+		if y, m, d := h.Hour.In(locale).Date(); c.Daily && y == year && m == month && d == day && h.KWh > today {
+			today = h.KWh
+		}
+	}
+	status.Peaks = c.peaks(completed)
+	for _, p := range status.Peaks {
+		status.PeakAverage += p.KWh
+	}
+	if len(status.Peaks) > 0 {
+		status.PeakAverage /= float64(len(status.Peaks))
+	}
+	status.Charge = status.PeakAverage * c.Rate
+
+	// The current hour does not raise the charge as long as it stays below
+	// the peak it would replace.
+	threshold := 0.0
+	switch {
+	case c.Daily && today >= 0 && today >= status.Peaks[len(status.Peaks)-1].KWh:
+		threshold = today
+	case len(status.Peaks) >= c.Peaks && len(status.Peaks) > 0:
+		threshold = status.Peaks[len(status.Peaks)-1].KWh
+	}
+	status.Headroom = max(0, threshold-status.CurrentHourKWh)
+	return status
+}
+
+// peaks returns the highest hours in descending order.
+func (c CapacityTariff) peaks(hours []Consumption) []Consumption {
+	var candidates []Consumption
+	if c.Daily {
+		daily := map[string]Consumption{}
+		for _, h := range hours {
+			key := h.Hour.In(locale).Format(time.DateOnly)
+			if best, ok := daily[key]; !ok || h.KWh > best.KWh {
+				daily[key] = h
+			}
+		}
+		candidates = make([]Consumption, 0, len(daily))
+		for _, h := range daily {
+			candidates = append(candidates, h)
+		}
+	} else {
+		candidates = append([]Consumption(nil), hours...)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].KWh != candidates[j].KWh {
+			return candidates[i].KWh > candidates[j].KWh
+		}
+		return candidates[i].Hour.Before(candidates[j].Hour)
+	})
+	if len(candidates) > c.Peaks {
+		candidates = candidates[:c.Peaks]
+	}
+	return candidates
+}
+
+// readConsumptionCSV parses time,kWh rows, the time being RFC3339 or local
+// time as 2006-01-02 15:04. A header row is skipped. Rows for the same hour
+// are summed.
+func readConsumptionCSV(r io.Reader) ([]Consumption, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	byHour := map[int64]float64{}
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading consumption csv: %v", err)
+		}
+		t, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			t, err = time.ParseInLocation("2006-01-02 15:04", record[0], locale)
+		}
+		if err != nil && line == 1 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid time on line %d: %v", line, err)
+		}
+		kwh, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kWh on line %d: %v", line, err)
+		}
+		byHour[t.Truncate(time.Hour).Unix()] += kwh
+	}
+	hours := make([]Consumption, 0, len(byHour))
+	for hour, kwh := range byHour {
+		hours = append(hours, Consumption{Hour: time.Unix(hour, 0).In(locale), KWh: kwh})
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Hour.Before(hours[j].Hour) })
+	return hours, nil
+}
+
+// queryConsumption sums the consumption samples per hour since from.
+func queryConsumption(ctx context.Context, queryAPI api.QueryAPI, bucket, measurement, field string, from time.Time) ([]Consumption, error) {
+	query := fmt.Sprintf(`from(bucket: %q)
+  |> range(start: %s)
+  |> filter(fn: (r) => r._measurement == %q and r._field == %q)
+  |> aggregateWindow(every: 1h, fn: sum, timeSrc: "_start", createEmpty: false)`,
+		bucket, from.UTC().Format(time.RFC3339), measurement, field)
+	result, err := queryAPI.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	var hours []Consumption
+	for result.Next() {
+		kwh, ok := result.Record().Value().(float64)
+		if !ok {
+			continue
+		}
+		hours = append(hours, Consumption{Hour: result.Record().Time(), KWh: kwh})
+	}
+	return hours, result.Err()
+}
+
+// CapacityTracker loads the consumption every -capacity-updaterate from the
+// CSV file or InfluxDB and writes the capacity tariff status to InfluxDB.
+func CapacityTracker(ctx context.Context, queryAPI api.QueryAPI, writeAPI api.WriteAPIBlocking) {
+	tariff := CapacityTariff{Peaks: *capacityPeaks, Daily: *capacityDaily, Rate: *capacityRate}
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, *capacityInterval)
+		now := clockSourceNow()
+		hours, err := loadConsumption(reqCtx, queryAPI, now)
+		if err != nil {
+			slog.Error("Loading consumption failed", "error", err)
+		} else {
+			status := tariff.Status(hours, now)
+			p := influxdb2.NewPointWithMeasurement("capacity").
+				AddTag("currency", "SEK").
+				AddField("charge", status.Charge).
+				AddField("peak_average", status.PeakAverage).
+				AddField("current_hour_kwh", status.CurrentHourKWh).
+				AddField("headroom", status.Headroom).
+				SetTime(now)
+			if err := writeAPI.WritePoint(reqCtx, p); err != nil {
+				slog.Error("Write capacity to influx failed", "error", err)
+			}
+		}
+		cancel()
+		if !sleep(ctx, *capacityInterval) {
+			return
+		}
+	}
+}
+
+func loadConsumption(ctx context.Context, queryAPI api.QueryAPI, now time.Time) ([]Consumption, error) {
+	if *capacityCSV != "" {
+		f, err := os.Open(*capacityCSV)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readConsumptionCSV(f)
+	}
+	year, month, _ := now.In(locale).Date()
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, locale)
+	return queryConsumption(ctx, queryAPI, *influxBucket, *capacityMeasurement, *capacityField, monthStart)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const consumptionCSV = `time,kwh
+2025-02-01 17:00,4
+2025-02-01 18:00,5
+2025-02-02 08:00,3
+2025-02-03 07:00,2.5
+2025-02-03 18:00,6
+2025-02-04 06:00,1.5
+2025-02-04 07:00,2
+2025-02-04 08:00,0.5
+`
+
+func TestReadConsumptionCSV(t *testing.T) {
+	hours, err := readConsumptionCSV(strings.NewReader("2025-02-01T17:00:00+01:00,1\n2025-02-01 17:00,0.5\n2025-02-01 18:00,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Consumption{
+		{Hour: time.Date(2025, 2, 1, 17, 0, 0, 0, locale), KWh: 1.5},
+		{Hour: time.Date(2025, 2, 1, 18, 0, 0, 0, locale), KWh: 2},
+	}
+	if diff := cmp.Diff(want, hours); diff != "" {
+		t.Errorf("readConsumptionCSV() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := readConsumptionCSV(strings.NewReader("time,kwh\n2025-02-01 17:00,lots\n")); err == nil {
+		t.Error("readConsumptionCSV() error got = nil, want error")
+	}
+}
+
+func TestCapacityStatus(t *testing.T) {
+	hours, err := readConsumptionCSV(strings.NewReader(consumptionCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := func(day, h int) time.Time { return time.Date(2025, 2, day, h, 0, 0, 0, locale) }
+	now := hour(4, 8).Add(20 * time.Minute)
+	tests := []struct {
+		name   string
+		tariff CapacityTariff
+		want   CapacityStatus
+	}{
+		{
+			name:   "daily peaks",
+			tariff: CapacityTariff{Peaks: 3, Daily: true, Rate: 80},
+			want: CapacityStatus{
+				Peaks:          []Consumption{{Hour: hour(3, 18), KWh: 6}, {Hour: hour(1, 18), KWh: 5}, {Hour: hour(2, 8), KWh: 3}},
+				PeakAverage:    14.0 / 3,
+				Charge:         14.0 / 3 * 80,
+				CurrentHourKWh: 0.5,
+				Headroom:       2.5,
+			},
+		},
+		{
+			name:   "hourly peaks",
+			tariff: CapacityTariff{Peaks: 3, Rate: 80},
+			want: CapacityStatus{
+				Peaks:          []Consumption{{Hour: hour(3, 18), KWh: 6}, {Hour: hour(1, 18), KWh: 5}, {Hour: hour(1, 17), KWh: 4}},
+				PeakAverage:    5,
+				Charge:         400,
+				CurrentHourKWh: 0.5,
+				Headroom:       3.5,
+			},
+		},
+		{
+			name:   "today holds a peak",
+			tariff: CapacityTariff{Peaks: 5, Daily: true, Rate: 80},
+			want: CapacityStatus{
+				Peaks:          []Consumption{{Hour: hour(3, 18), KWh: 6}, {Hour: hour(1, 18), KWh: 5}, {Hour: hour(2, 8), KWh: 3}, {Hour: hour(4, 7), KWh: 2}},
+				PeakAverage:    4,
+				Charge:         320,
+				CurrentHourKWh: 0.5,
+				Headroom:       1.5,
+			},
+		},
+		{
+			name:   "month not filled",
+			tariff: CapacityTariff{Peaks: 10, Rate: 80},
+			want: CapacityStatus{
+				Peaks: []Consumption{
+					{Hour: hour(3, 18), KWh: 6}, {Hour: hour(1, 18), KWh: 5}, {Hour: hour(1, 17), KWh: 4}, {Hour: hour(2, 8), KWh: 3},
+					{Hour: hour(3, 7), KWh: 2.5}, {Hour: hour(4, 7), KWh: 2}, {Hour: hour(4, 6), KWh: 1.5},
+				},
+				PeakAverage:    24.0 / 7,
+				Charge:         24.0 / 7 * 80,
+				CurrentHourKWh: 0.5,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.tariff.Status(hours, now)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("Status() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
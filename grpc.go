@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"price2influx/pricepb"
+)
+
+var grpcAddr = flag.String("grpcaddr", "", "Address for the gRPC API to listen on, disabled if empty")
+
+// priceServer implements the gRPC PriceService on the price clients.
+type priceServer struct {
+	pricepb.UnimplementedPriceServiceServer
+	pcs    PriceClients
+	stream *priceStream
+}
+
+// newGRPCServer returns the gRPC server served on -grpcaddr.
+func newGRPCServer(pcs PriceClients, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	pricepb.RegisterPriceServiceServer(s, &priceServer{pcs: pcs, stream: newPriceStream(pcs)})
+	return s
+}
+
+func pbPrice(p Price) *pricepb.Price {
+	return &pricepb.Price{
+		SekPerKwh: p.SEKPerkWh,
+		EurPerKwh: p.EURPerkWh,
+		Exr:       p.EXR,
+		TimeStart: timestamppb.New(p.TimeStart),
+		TimeEnd:   timestamppb.New(p.TimeEnd),
+	}
+}
+
+func pbPrices(ps Prices) []*pricepb.Price {
+	res := make([]*pricepb.Price, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, pbPrice(p))
+	}
+	return res
+}
+
+// zone returns the client of the zone, the first configured if empty.
+func (s *priceServer) zone(zone string) (*PriceClient, error) {
+	pc := s.pcs.Zone(zone)
+	if pc == nil {
+		return nil, status.Errorf(codes.NotFound, "unknown zone %q", zone)
+	}
+	return pc, nil
+}
+
+func (s *priceServer) GetCurrentPrice(ctx context.Context, req *pricepb.GetCurrentPriceRequest) (*pricepb.GetCurrentPriceResponse, error) {
+	pc, err := s.zone(req.GetZone())
+	if err != nil {
+		return nil, err
+	}
+	price, err := pc.CurrentPrice(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pricepb.GetCurrentPriceResponse{Zone: pc.Zone(), Price: pbPrice(price), Level: pc.Today().Level(price)}, nil
+}
+
+func (s *priceServer) GetSchedule(ctx context.Context, req *pricepb.GetScheduleRequest) (*pricepb.GetScheduleResponse, error) {
+	pc, err := s.zone(req.GetZone())
+	if err != nil {
+		return nil, err
+	}
+	year, month, day := clockSourceNow().In(locale).Date()
+	from := time.Date(year, month, day, 0, 0, 0, 0, locale)
+	if req.From != nil {
+		from = req.From.AsTime()
+	}
+	var to time.Time
+	if req.To != nil {
+		to = req.To.AsTime()
+	} else if schedule := pc.Schedule(ctx); len(schedule) > 0 {
+		to = schedule[len(schedule)-1].TimeEnd
+	}
+	if !to.After(from) {
+		return nil, status.Errorf(codes.InvalidArgument, "to %v must be after from %v", to, from)
+	}
+	if to.Sub(from) > maxRangeDays*24*time.Hour {
+		return nil, status.Errorf(codes.InvalidArgument, "range longer than %d days", maxRangeDays)
+	}
+
+	res := &pricepb.GetScheduleResponse{Zone: pc.Zone()}
+	year, month, day = from.In(locale).Date()
+	for date := time.Date(year, month, day, 0, 0, 0, 0, locale); date.Before(to); date = date.AddDate(0, 0, 1) {
+		prices, err := pc.PricesFor(date)
+		if errors.Is(err, errNotPublished) {
+			break
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		for _, p := range prices {
+			if p.TimeEnd.After(from) && p.TimeStart.Before(to) {
+				res.Prices = append(res.Prices, pbPrice(p))
+			}
+		}
+	}
+	return res, nil
+}
+
+func (s *priceServer) FindCheapestWindow(ctx context.Context, req *pricepb.FindCheapestWindowRequest) (*pricepb.FindCheapestWindowResponse, error) {
+	pc, err := s.zone(req.GetZone())
+	if err != nil {
+		return nil, err
+	}
+	if req.Duration == nil {
+		return nil, status.Error(codes.InvalidArgument, "duration is required")
+	}
+	schedule := pc.Schedule(ctx)
+	plan := PlanRequest{Duration: req.Duration.AsDuration(), Earliest: clockSourceNow(), Profile: req.Profile}
+	if req.Earliest != nil {
+		plan.Earliest = req.Earliest.AsTime()
+	}
+	if req.Deadline != nil {
+		plan.Deadline = req.Deadline.AsTime()
+	} else if len(schedule) > 0 {
+		plan.Deadline = schedule[len(schedule)-1].TimeEnd
+	}
+	res, err := planCheapest(schedule, plan)
+	if errors.Is(err, errNoPrices) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &pricepb.FindCheapestWindowResponse{
+		Start:         timestamppb.New(res.Start),
+		End:           timestamppb.New(res.End),
+		Cost:          res.Cost,
+		IntervalsCost: res.IntervalsCost,
+	}
+	for _, i := range res.Intervals {
+		resp.Intervals = append(resp.Intervals, &pricepb.PlanInterval{Start: timestamppb.New(i.Start), End: timestamppb.New(i.End), Cost: i.Cost})
+	}
+	return resp, nil
+}
+
+// WatchPrices streams the events of the price stream, see priceStream.
+func (s *priceServer) WatchPrices(req *pricepb.WatchPricesRequest, stream grpc.ServerStreamingServer[pricepb.WatchPricesResponse]) error {
+	zones, err := s.stream.zones(req.GetZones())
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	ch, replay := s.stream.subscribe(zones)
+	defer s.stream.unsubscribe(ch)
+	for _, e := range replay {
+		if err := stream.Send(pbWatchEvent(e)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber too slow")
+			}
+			if err := stream.Send(pbWatchEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func pbWatchEvent(e streamEvent) *pricepb.WatchPricesResponse {
+	res := &pricepb.WatchPricesResponse{Id: e.ID, Zone: e.Zone}
+	switch data := e.Data.(type) {
+	case streamPrice:
+		res.Event = &pricepb.WatchPricesResponse_PriceChanged{PriceChanged: &pricepb.PriceChanged{Price: pbPrice(data.Price), Level: data.Level}}
+	case streamTomorrow:
+		res.Event = &pricepb.WatchPricesResponse_TomorrowPublished{TomorrowPublished: &pricepb.TomorrowPublished{Prices: pbPrices(data.Prices)}}
+	case streamStale:
+		res.Event = &pricepb.WatchPricesResponse_Stale{Stale: &pricepb.Stale{Stale: data.Stale, LoadedAt: timestamppb.New(data.LoadedAt)}}
+	}
+	return res
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"price2influx/pricepb"
+)
+
+func newTestGRPCClient(t *testing.T, pcs PriceClients) pricepb.PriceServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(pcs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pricepb.NewPriceServiceClient(conn)
+}
+
+func TestGRPCPriceService(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	client := newTestGRPCClient(t, PriceClients{loadedPriceClient(t)})
+	ctx := context.Background()
+
+	current, err := client.GetCurrentPrice(ctx, &pricepb.GetCurrentPriceRequest{Zone: "SE3"})
+	if err != nil {
+		t.Fatalf("GetCurrentPrice() error = %v", err)
+	}
+	if current.Price.SekPerKwh != 0.78352 || current.Level != "normal" || !current.Price.TimeStart.AsTime().Equal(time.Date(2025, 2, 2, 10, 0, 0, 0, locale)) {
+		t.Errorf("GetCurrentPrice() got = %v", current)
+	}
+	if _, err := client.GetCurrentPrice(ctx, &pricepb.GetCurrentPriceRequest{Zone: "SE1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetCurrentPrice(SE1) error = %v, want NotFound", err)
+	}
+
+	schedules := []struct {
+		name     string
+		req      *pricepb.GetScheduleRequest
+		wantLen  int
+		wantCode codes.Code
+	}{
+		{name: "loaded", req: &pricepb.GetScheduleRequest{}, wantLen: 48},
+		{
+			name: "range",
+			req: &pricepb.GetScheduleRequest{
+				From: timestamppb.New(time.Date(2025, 2, 2, 22, 30, 0, 0, locale)),
+				To:   timestamppb.New(time.Date(2025, 2, 3, 2, 0, 0, 0, locale)),
+			},
+			wantLen: 4,
+		},
+		{
+			name:     "reversed",
+			req:      &pricepb.GetScheduleRequest{From: timestamppb.New(time.Date(2025, 2, 3, 0, 0, 0, 0, locale)), To: timestamppb.New(time.Date(2025, 2, 2, 0, 0, 0, 0, locale))},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range schedules {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.GetSchedule(ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GetSchedule() error = %v, want code %v", err, tt.wantCode)
+			}
+			if len(res.GetPrices()) != tt.wantLen {
+				t.Errorf("GetSchedule() prices got = %d, want = %d", len(res.GetPrices()), tt.wantLen)
+			}
+		})
+	}
+
+	plan, err := client.FindCheapestWindow(ctx, &pricepb.FindCheapestWindowRequest{
+		Duration: durationpb.New(2 * time.Hour),
+		Earliest: timestamppb.New(time.Date(2025, 2, 2, 20, 0, 0, 0, locale)),
+		Deadline: timestamppb.New(time.Date(2025, 2, 3, 6, 0, 0, 0, locale)),
+	})
+	if err != nil {
+		t.Fatalf("FindCheapestWindow() error = %v", err)
+	}
+	if want := time.Date(2025, 2, 3, 2, 0, 0, 0, locale); !plan.Start.AsTime().Equal(want) {
+		t.Errorf("FindCheapestWindow() start got = %v, want = %v", plan.Start.AsTime(), want)
+	}
+	if _, err := client.FindCheapestWindow(ctx, &pricepb.FindCheapestWindowRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("FindCheapestWindow() without duration error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCWatchPrices(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	client := newTestGRPCClient(t, PriceClients{loadedPriceClient(t)})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchPrices(ctx, &pricepb.WatchPricesRequest{Zones: []string{"SE3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The current state is replayed on connect.
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if first.Zone != "SE3" || first.GetPriceChanged().GetPrice().GetSekPerKwh() != 0.78352 {
+		t.Errorf("first event got = %v", first)
+	}
+	second, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if len(second.GetTomorrowPublished().GetPrices()) != 24 {
+		t.Errorf("second event got = %v", second)
+	}
+
+	// Duplicate zones are replayed once.
+	var zones []string
+	for i := 0; i < 100; i++ {
+		zones = append(zones, "SE3")
+	}
+	stream, err = client.WatchPrices(ctx, &pricepb.WatchPricesRequest{Zones: zones})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"price", "tomorrow"} {
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		if got := (res.GetPriceChanged() != nil && want == "price") || (res.GetTomorrowPublished() != nil && want == "tomorrow"); !got {
+			t.Errorf("replayed event got = %v, want %s", res, want)
+		}
+	}
+
+	stream, err = client.WatchPrices(ctx, &pricepb.WatchPricesRequest{Zones: []string{"SE4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("WatchPrices(SE4) error = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ZoneStatus is the data freshness of a zone reported on /status.
+type ZoneStatus struct {
+	Zone              string    `json:"zone"`
+	TodayLoaded       bool      `json:"today_loaded"`
+	TomorrowLoaded    bool      `json:"tomorrow_loaded"`
+	LoadedAt          time.Time `json:"loaded_at,omitzero"`
+	LastFetchError    string    `json:"last_fetch_error,omitempty"`
+	LastFetchErrorAt  time.Time `json:"last_fetch_error_at,omitzero"`
+	NextRefresh       time.Time `json:"next_refresh,omitzero"`
+	NextTomorrowFetch time.Time `json:"next_tomorrow_fetch,omitzero"`
+}
+
+// Status is the body of /status.
+type Status struct {
+	Ready bool `json:"ready"`
+	// Reasons lists why the process is not ready.
+	Reasons []string      `json:"reasons,omitempty"`
+	Zones   []ZoneStatus  `json:"zones"`
+	Sinks   []RouteStatus `json:"sinks"`
+}
+
+// healthChecker serves the liveness, readiness and status endpoints. The
+// process is ready when today's prices are loaded for every zone and the
+// first route, the primary sink, accepted a write within the last
+// three intervals.
+type healthChecker struct {
+	pcs    PriceClients
+	routes []*sinkRoute
+}
+
+func newHealthChecker(pcs PriceClients, routes []*sinkRoute) *healthChecker {
+	return &healthChecker{pcs: pcs, routes: routes}
+}
+
+func (p *PriceClient) status() ZoneStatus {
+	now := clockSourceNow()
+	_, today := p.LoadedFor(now)
+	_, tomorrow := p.LoadedFor(now.In(locale).AddDate(0, 0, 1))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := ZoneStatus{
+		Zone:              p.priceClass,
+		TodayLoaded:       today,
+		TomorrowLoaded:    tomorrow,
+		LoadedAt:          p.loadedAt,
+		LastFetchErrorAt:  p.fetchErrAt,
+		NextRefresh:       p.nextRefresh,
+		NextTomorrowFetch: p.nextTomorrow,
+	}
+	if p.fetchErr != nil {
+		status.LastFetchError = p.fetchErr.Error()
+	}
+	return status
+}
+
+func (h *healthChecker) status() Status {
+	status := Status{Zones: []ZoneStatus{}, Sinks: []RouteStatus{}}
+	for _, pc := range h.pcs {
+		zone := pc.status()
+		if !zone.TodayLoaded {
+			status.Reasons = append(status.Reasons, fmt.Sprintf("today's prices of %s not loaded", zone.Zone))
+		}
+		status.Zones = append(status.Zones, zone)
+	}
+	for i, route := range h.routes {
+		sink := route.status()
+		if i == 0 {
+			switch {
+			case sink.Paused:
+			case sink.Disabled:
+				status.Reasons = append(status.Reasons, fmt.Sprintf("primary sink %s disabled", sink.Name))
+			case clockSourceNow().Sub(sink.LastWrite) > 3*route.interval:
+				status.Reasons = append(status.Reasons, fmt.Sprintf("no recent write to primary sink %s", sink.Name))
+			}
+		}
+		status.Sinks = append(status.Sinks, sink)
+	}
+	status.Ready = len(status.Reasons) == 0
+	return status
+}
+
+// register adds /healthz, /readyz and /status to mux.
+func (h *healthChecker) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := h.status()
+		if !status.Ready {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			for _, reason := range status.Reasons {
+				fmt.Fprintln(w, reason)
+			}
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, h.status())
+	})
+}
+
+// withHealth serves the endpoints of h next to handler.
+func withHealth(handler http.Handler, h *healthChecker) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	h.register(mux)
+	return mux
+}
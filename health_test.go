@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	loaded := loadedPriceClient(t)
+	loaded.loadedAt = time.Date(2025, 2, 2, 0, 0, 1, 0, locale)
+	loaded.nextRefresh = time.Date(2025, 2, 3, 0, 0, 1, 0, locale)
+	empty := &PriceClient{priceClass: "SE4", fetchErr: errors.New("upstream down"), fetchErrAt: fakec.curtime}
+
+	tests := []struct {
+		name        string
+		pcs         PriceClients
+		lastWrite   time.Time
+		paused      bool
+		wantReady   bool
+		wantReasons int
+	}{
+		{name: "ready", pcs: PriceClients{loaded}, lastWrite: fakec.curtime.Add(-10 * time.Second), wantReady: true},
+		{name: "stale write", pcs: PriceClients{loaded}, lastWrite: fakec.curtime.Add(-time.Hour), wantReasons: 1},
+		{name: "paused", pcs: PriceClients{loaded}, paused: true, wantReady: true},
+		{name: "zone not loaded", pcs: PriceClients{loaded, empty}, lastWrite: fakec.curtime, wantReasons: 1},
+		{name: "nothing", pcs: PriceClients{empty}, wantReasons: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := newSinkRoute(&recordSink{})
+			route.interval = 10 * time.Second
+			route.lastWrite = tt.lastWrite
+			route.paused.Store(tt.paused)
+			ts := httptest.NewServer(withHealth(http.NotFoundHandler(), newHealthChecker(tt.pcs, []*sinkRoute{route})))
+			defer ts.Close()
+
+			for path, want := range map[string]int{"/healthz": http.StatusOK, "/other": http.StatusNotFound} {
+				resp, err := http.Get(ts.URL + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("%s status got = %d, want = %d", path, resp.StatusCode, want)
+				}
+			}
+
+			resp, err := http.Get(ts.URL + "/readyz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.StatusCode == http.StatusOK; got != tt.wantReady {
+				t.Errorf("/readyz status got = %d, want ready = %v", resp.StatusCode, tt.wantReady)
+			}
+
+			resp, err = http.Get(ts.URL + "/status")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var status Status
+			if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+				t.Fatal(err)
+			}
+			if status.Ready != tt.wantReady || len(status.Reasons) != tt.wantReasons || len(status.Zones) != len(tt.pcs) || len(status.Sinks) != 1 {
+				t.Errorf("/status got = %+v", status)
+			}
+		})
+	}
+
+	zone := loaded.status()
+	want := ZoneStatus{
+		Zone:           "SE3",
+		TodayLoaded:    true,
+		TomorrowLoaded: true,
+		LoadedAt:       loaded.loadedAt,
+		NextRefresh:    loaded.nextRefresh,
+	}
+	if zone != want {
+		t.Errorf("status() got = %+v, want = %+v", zone, want)
+	}
+	if zone := empty.status(); zone.TodayLoaded || zone.LastFetchError != "upstream down" {
+		t.Errorf("status() got = %+v", zone)
+	}
+}
+
+// blockingSink blocks writes until their context is done.
+type blockingSink struct {
+	writing chan struct{}
+}
+
+func (s *blockingSink) Name() string {
+	return "blocking"
+}
+
+func (s *blockingSink) Write(ctx context.Context, samples []Sample) error {
+	close(s.writing)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHealthCheckerSlowSink(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	sink := &blockingSink{writing: make(chan struct{})}
+	route := newSinkRoute(sink)
+	route.interval, route.timeout = 10*time.Second, time.Minute
+	route.lastWrite = fakec.curtime
+	ts := httptest.NewServer(withHealth(http.NotFoundHandler(), newHealthChecker(PriceClients{loadedPriceClient(t)}, []*sinkRoute{route})))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		route.writeTraced(ctx, []Sample{{Zone: "SE3"}})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	<-sink.writing
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("/readyz during a write error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/readyz status got = %d, want = %d", resp.StatusCode, http.StatusOK)
+	}
+}
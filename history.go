@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	_ "modernc.org/sqlite"
+)
+
+var historyPath = flag.String("history", "", "SQLite file every loaded price interval is kept in, disabled if empty")
+
+// HistoryStore keeps price intervals in a SQLite database, keyed by zone
+// and start time.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+// DayStats are the price statistics of one local day.
+type DayStats struct {
+	Date      string `json:"date"`
+	Intervals int    `json:"intervals"`
+	Stats
+}
+
+func openHistory(path string) (*HistoryStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, serialize access instead of failing
+	// with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS prices (
+	zone TEXT NOT NULL,
+	time_start INTEGER NOT NULL,
+	time_end INTEGER NOT NULL,
+	sek REAL NOT NULL,
+	eur REAL NOT NULL,
+	exr REAL NOT NULL,
+	PRIMARY KEY (zone, time_start)
+) WITHOUT ROWID`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating history table in %s: %v", path, err)
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Save stores the intervals of the zone, replacing stored intervals with
+// the same start time.
+func (h *HistoryStore) Save(ctx context.Context, zone string, prices Prices) error {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO prices (zone, time_start, time_end, sek, eur, exr)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (zone, time_start) DO UPDATE SET
+	time_end = excluded.time_end,
+	sek = excluded.sek,
+	eur = excluded.eur,
+	exr = excluded.exr`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range prices {
+		if _, err := stmt.ExecContext(ctx, zone, p.TimeStart.Unix(), p.TimeEnd.Unix(), p.SEKPerkWh, p.EURPerkWh, p.EXR); err != nil {
+			return fmt.Errorf("error saving prices: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Range returns the stored intervals of the zone starting in [from, to).
+func (h *HistoryStore) Range(ctx context.Context, zone string, from, to time.Time) (Prices, error) {
+	rows, err := h.db.QueryContext(ctx, `SELECT time_start, time_end, sek, eur, exr FROM prices
+WHERE zone = ? AND time_start >= ? AND time_start < ? ORDER BY time_start`, zone, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	prices := Prices{}
+	for rows.Next() {
+		var p Price
+		var start, end int64
+		if err := rows.Scan(&start, &end, &p.SEKPerkWh, &p.EURPerkWh, &p.EXR); err != nil {
+			return nil, err
+		}
+		p.TimeStart = time.Unix(start, 0).In(locale)
+		p.TimeEnd = time.Unix(end, 0).In(locale)
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// Daily returns the statistics in currency of every local day with stored
+// intervals of the zone in [from, to).
+func (h *HistoryStore) Daily(ctx context.Context, zone string, from, to time.Time, currency string) ([]DayStats, error) {
+	prices, err := h.Range(ctx, zone, from, to)
+	if err != nil {
+		return nil, err
+	}
+	days := []DayStats{}
+	for len(prices) > 0 {
+		date := prices[0].TimeStart.Format(time.DateOnly)
+		n := 1
+		for n < len(prices) && prices[n].TimeStart.Format(time.DateOnly) == date {
+			n++
+		}
+		days = append(days, DayStats{Date: date, Intervals: n, Stats: prices[:n].Stats(currency)})
+		prices = prices[n:]
+	}
+	return days, nil
+}
+
+// writeHistoryCSV writes the intervals as CSV with a header row.
+func writeHistoryCSV(w io.Writer, zone string, prices Prices) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"zone", "time_start", "time_end", "SEK_per_kWh", "EUR_per_kWh", "EXR"})
+	for _, p := range prices {
+		cw.Write([]string{
+			zone,
+			p.TimeStart.Format(time.RFC3339),
+			p.TimeEnd.Format(time.RFC3339),
+			strconv.FormatFloat(p.SEKPerkWh, 'f', -1, 64),
+			strconv.FormatFloat(p.EURPerkWh, 'f', -1, 64),
+			strconv.FormatFloat(p.EXR, 'f', -1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// historyPoints returns the intervals as price points timestamped at the
+// start of each interval.
+func historyPoints(zone string, prices Prices, tariff *Tariff) []*write.Point {
+	points := make([]*write.Point, 0, len(prices))
+	for _, p := range prices {
+		points = append(points, pricePoint(zone, p, tariff, p.TimeStart))
+	}
+	return points
+}
+
+// historySink saves the schedule of every zone to the history store
+// whenever a zone has loaded new intervals.
+type historySink struct {
+	store *HistoryStore
+	pcs   PriceClients
+
+	mu      sync.Mutex
+	written map[string]time.Time
+}
+
+func (s *historySink) Name() string {
+	return "history"
+}
+
+func (s *historySink) Write(ctx context.Context, samples []Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.written == nil {
+		s.written = map[string]time.Time{}
+	}
+	for _, sample := range samples {
+		pc := s.pcs.Zone(sample.Zone)
+		if pc == nil {
+			continue
+		}
+		schedule := pc.Schedule(ctx)
+		if len(schedule) == 0 || s.written[sample.Zone].Equal(schedule[len(schedule)-1].TimeEnd) {
+			continue
+		}
+		if err := s.store.Save(ctx, sample.Zone, schedule); err != nil {
+			return err
+		}
+		s.written[sample.Zone] = schedule[len(schedule)-1].TimeEnd
+	}
+	return nil
+}
+
+// UpsertPrices saves the intervals of the zone.
+func (s *historySink) UpsertPrices(ctx context.Context, zone string, prices Prices) error {
+	return s.store.Save(ctx, zone, prices)
+}
+
+// parseHistoryRange parses the from and to of a history query, dates or
+// times as accepted by parseTime. from defaults to the start of today and
+// to to one day after from.
+func parseHistoryRange(fromStr, toStr string) (from, to time.Time, err error) {
+	parse := func(s string) (time.Time, error) {
+		if t, err := time.ParseInLocation(time.DateOnly, s, locale); err == nil {
+			return t, nil
+		}
+		return parseTime(s)
+	}
+	if fromStr == "" {
+		year, month, day := clockSourceNow().In(locale).Date()
+		from = time.Date(year, month, day, 0, 0, 0, 0, locale)
+	} else if from, err = parse(fromStr); err != nil {
+		return from, to, fmt.Errorf("invalid from: %v", err)
+	}
+	if toStr == "" {
+		to = from.AddDate(0, 0, 1)
+	} else if to, err = parse(toStr); err != nil {
+		return from, to, fmt.Errorf("invalid to: %v", err)
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to %v must be after from %v", to, from)
+	}
+	return from, to, nil
+}
+
+// historyHandler serves the stored intervals of the zone query parameter.
+// The kind is range for the intervals as JSON, daily for daily statistics
+// in the currency query parameter or export for the intervals as CSV.
+func historyHandler(store *HistoryStore, pcs PriceClients, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		zone := q.Get("zone")
+		if zone == "" {
+			zone = pcs[0].Zone()
+		}
+		if !slices.Contains(priceClasses, zone) {
+			http.Error(w, fmt.Sprintf("unknown zone %q", zone), http.StatusNotFound)
+			return
+		}
+		from, to, err := parseHistoryRange(q.Get("from"), q.Get("to"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch kind {
+		case "daily":
+			currency := q.Get("currency")
+			if currency == "" {
+				currency = "SEK"
+			}
+			if !slices.Contains(currencies, currency) {
+				http.Error(w, fmt.Sprintf("currency must be one of %v", currencies), http.StatusBadRequest)
+				return
+			}
+			days, err := store.Daily(r.Context(), zone, from, to, currency)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, days)
+		default:
+			prices, err := store.Range(r.Context(), zone, from, to)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if kind == "export" {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s_%s_%s.csv", zone, from.Format(time.DateOnly), to.Format(time.DateOnly))))
+				writeHistoryCSV(w, zone, prices)
+				return
+			}
+			writeJSON(w, prices)
+		}
+	}
+}
+
+// runHistory implements the history subcommand:
+//
+//	price2influx history range|daily|export|backfill [flags]
+func runHistory(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s history range|daily|export|backfill [flags]", os.Args[0])
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("history "+cmd, flag.ExitOnError)
+	path := fs.String("history", "price2influx.db", "SQLite history file")
+	class := fs.String("priceclass", "SE3", fmt.Sprintf("Priceclass, one of: %v. backfill takes several, comma separated", priceClasses))
+	fromStr := fs.String("from", "", "Start, 2006-01-02, RFC3339 or 2006-01-02T15:04 local time, defaults to today")
+	toStr := fs.String("to", "", "End (exclusive), defaults to one day after -from")
+	currency := fs.String("currency", "SEK", fmt.Sprintf("Currency of daily statistics, one of: %v", currencies))
+	// backfill writes with the InfluxDB settings of the main command.
+	fs.StringVar(influxAddr, "influxaddr", *influxAddr, "InfluxDB address to backfill")
+	fs.StringVar(influxToken, "influxtoken", *influxToken, "InfluxDB token")
+	fs.StringVar(influxOrg, "influxorg", *influxOrg, "InfluxDB organisation")
+	fs.StringVar(influxBucket, "influxbucket", *influxBucket, "InfluxDB bucket")
+	fs.StringVar(tariffFile, "tariff", *tariffFile, "JSON file with taxes, fees and VAT added to the backfilled prices")
+	fs.BoolVar(priceClassTag, "priceclass-tag", *priceClassTag, "Tag the backfilled price points with the priceclass, always on if several priceclasses are given")
+	fs.Parse(args[1:])
+
+	zones, err := parseZones(*class)
+	if err != nil {
+		return err
+	}
+	if len(zones) > 1 {
+		if cmd != "backfill" {
+			return fmt.Errorf("history %s takes a single priceclass", cmd)
+		}
+		*priceClassTag = true
+	}
+	zone := zones[0]
+	from, to, err := parseHistoryRange(*fromStr, *toStr)
+	if err != nil {
+		return err
+	}
+	store, err := openHistory(*path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	switch cmd {
+	case "range", "export":
+		prices, err := store.Range(ctx, zone, from, to)
+		if err != nil {
+			return err
+		}
+		if cmd == "export" {
+			return writeHistoryCSV(os.Stdout, zone, prices)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(prices)
+	case "daily":
+		if !slices.Contains(currencies, *currency) {
+			return fmt.Errorf("currency must be one of %v", currencies)
+		}
+		days, err := store.Daily(ctx, zone, from, to, *currency)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(days)
+	case "backfill":
+		var tariff *Tariff
+		if *tariffFile != "" {
+			if tariff, err = loadTariff(*tariffFile); err != nil {
+				return err
+			}
+		}
+		client := newInfluxClient(*influxAddr, *influxToken)
+		defer client.Close()
+		writeAPI := client.WriteAPIBlocking(*influxOrg, *influxBucket)
+		for _, zone := range zones {
+			prices, err := store.Range(ctx, zone, from, to)
+			if err != nil {
+				return err
+			}
+			if len(prices) == 0 {
+				continue
+			}
+			if err := writeAPI.WritePoint(ctx, historyPoints(zone, prices, tariff)...); err != nil {
+				return err
+			}
+			slog.Info("Backfilled history", "zone", zone, "intervals", len(prices), "influxaddr", *influxAddr)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown history command %q, one of: range, daily, export, backfill", cmd)
+}
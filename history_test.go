@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHistoryStore(t *testing.T) {
+	store, err := openHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("openHistory() error = %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	pc := loadedPriceClient(t)
+	sink := &historySink{store: store, pcs: PriceClients{pc}}
+	// A repeated write must not duplicate the intervals.
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(ctx, []Sample{{Zone: "SE3"}}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		sink.written = nil
+	}
+
+	day1, day2 := pc.Today(), pc.Schedule(context.Background())[len(pc.Today()):]
+	from := day1[0].TimeStart
+	to := day2[len(day2)-1].TimeEnd
+	got, err := store.Range(ctx, "SE3", from, to)
+	if err != nil {
+		t.Fatalf("Range() error = %v", err)
+	}
+	if diff := cmp.Diff(pc.Schedule(context.Background()), got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("Range() mismatch (-want +got):\n%s", diff)
+	}
+	if got, err := store.Range(ctx, "SE4", from, to); err != nil || len(got) != 0 {
+		t.Errorf("Range() of another zone got = %v, %v, want = empty", got, err)
+	}
+
+	days, err := store.Daily(ctx, "SE3", from, to, "SEK")
+	if err != nil {
+		t.Fatalf("Daily() error = %v", err)
+	}
+	wantDays := []DayStats{
+		{Date: day1[0].TimeStart.In(locale).Format(time.DateOnly), Intervals: len(day1), Stats: day1.Stats("SEK")},
+		{Date: day2[0].TimeStart.In(locale).Format(time.DateOnly), Intervals: len(day2), Stats: day2.Stats("SEK")},
+	}
+	if diff := cmp.Diff(wantDays, days); diff != "" {
+		t.Errorf("Daily() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Backfilled corrections replace the stored interval.
+	corrected := day1[0]
+	corrected.SEKPerkWh = 9.99
+	if err := store.Save(ctx, "SE3", Prices{corrected}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err = store.Range(ctx, "SE3", from, from.Add(time.Minute))
+	if err != nil || len(got) != 1 || got[0].SEKPerkWh != 9.99 {
+		t.Errorf("Range() after Save() got = %v, %v, want = [%v]", got, err, corrected)
+	}
+}
+
+func TestHistoryHandler(t *testing.T) {
+	store, err := openHistory(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	pc := loadedPriceClient(t)
+	if err := store.Save(context.Background(), "SE3", pc.Schedule(context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{pc}, store, nil))
+	defer ts.Close()
+	date := pc.Today()[0].TimeStart.In(locale).Format(time.DateOnly)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "range", query: "/api/v1/history?from=" + date, wantStatus: http.StatusOK, wantBody: `"SEK_per_kWh"`},
+		{name: "daily", query: "/api/v1/history/daily?currency=EUR&from=" + date, wantStatus: http.StatusOK, wantBody: `"date":"` + date + `"`},
+		{name: "export", query: "/api/v1/history/export?zone=SE3&from=" + date, wantStatus: http.StatusOK, wantBody: "zone,time_start,time_end,SEK_per_kWh,EUR_per_kWh,EXR\nSE3,"},
+		{name: "unknown zone", query: "/api/v1/history?zone=SE9", wantStatus: http.StatusNotFound},
+		{name: "invalid range", query: "/api/v1/history?from=" + date + "&to=" + date, wantStatus: http.StatusBadRequest},
+		{name: "invalid currency", query: "/api/v1/history/daily?currency=USD&from=" + date, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status got = %d, want = %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body got = %s, want to contain %s", body, tt.wantBody)
+			}
+		})
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/history?from=" + date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var prices Prices
+	if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != len(pc.Today()) {
+		t.Errorf("range intervals got = %d, want = %d", len(prices), len(pc.Today()))
+	}
+}
+
+func TestRunHistoryBackfillZones(t *testing.T) {
+	defer func(addr string, tag bool) { *influxAddr, *priceClassTag = addr, tag }(*influxAddr, *priceClassTag)
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := openHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, locale)
+	for zone, sek := range map[string]float64{"SE3": 1, "SE4": 2} {
+		if err := store.Save(context.Background(), zone, hourlyPrices(start, sek, sek)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	var lines []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(b)), "\n")...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	if err := runHistory([]string{"range", "-history", path, "-priceclass", "SE3,SE4"}); err == nil {
+		t.Errorf("runHistory() range of two zones error = nil, want an error")
+	}
+	if err := runHistory([]string{"backfill", "-history", path, "-priceclass", "SE3,SE4", "-from", "2025-01-15", "-influxaddr", ts.URL}); err != nil {
+		t.Fatalf("runHistory() error = %v", err)
+	}
+	var tagged []string
+	for _, line := range lines {
+		tagged = append(tagged, strings.Split(line, " ")[0])
+	}
+	want := []string{
+		"price,currency=SEK,priceclass=SE3", "price,currency=SEK,priceclass=SE3",
+		"price,currency=SEK,priceclass=SE4", "price,currency=SEK,priceclass=SE4",
+	}
+	if diff := cmp.Diff(want, tagged); diff != "" {
+		t.Errorf("backfilled series mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+)
+
+// Holiday is a Swedish public holiday, Eve marks the de facto holidays
+// (Midsummer Eve, Christmas Eve and New Year's Eve) that are not public
+// holidays by law but are treated as such by most grid operators.
+type Holiday struct {
+	Date time.Time
+	Name string
+	Eve  bool
+}
+
+// SwedishHolidays returns the public holidays and eves of the year in date order.
+func SwedishHolidays(year int) []Holiday {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, locale)
+	}
+	// saturdayFrom returns the first Saturday on or after the date.
+	saturdayFrom := func(month time.Month, day int) time.Time {
+		d := date(month, day)
+		return d.AddDate(0, 0, (int(time.Saturday)-int(d.Weekday())+7)%7)
+	}
+	easter := easterSunday(year)
+	midsummer := saturdayFrom(time.June, 20)
+	return []Holiday{
+		{Date: date(time.January, 1), Name: "Nyårsdagen"},
+		{Date: date(time.January, 6), Name: "Trettondedag jul"},
+		{Date: easter.AddDate(0, 0, -2), Name: "Långfredagen"},
+		{Date: easter, Name: "Påskdagen"},
+		{Date: easter.AddDate(0, 0, 1), Name: "Annandag påsk"},
+		{Date: date(time.May, 1), Name: "Första maj"},
+		{Date: easter.AddDate(0, 0, 39), Name: "Kristi himmelsfärdsdag"},
+		{Date: easter.AddDate(0, 0, 49), Name: "Pingstdagen"},
+		{Date: date(time.June, 6), Name: "Sveriges nationaldag"},
+		{Date: midsummer.AddDate(0, 0, -1), Name: "Midsommarafton", Eve: true},
+		{Date: midsummer, Name: "Midsommardagen"},
+		{Date: saturdayFrom(time.October, 31), Name: "Alla helgons dag"},
+		{Date: date(time.December, 24), Name: "Julafton", Eve: true},
+		{Date: date(time.December, 25), Name: "Juldagen"},
+		{Date: date(time.December, 26), Name: "Annandag jul"},
+		{Date: date(time.December, 31), Name: "Nyårsafton", Eve: true},
+	}
+}
+
+// IsSwedishHoliday reports whether t falls on a Swedish public holiday or eve.
+func IsSwedishHoliday(t time.Time) bool {
+	year, month, day := t.In(locale).Date()
+	for _, h := range SwedishHolidays(year) {
+		if h.Date.Month() == month && h.Date.Day() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// easterSunday computes the date of Easter Sunday in the Gregorian calendar
+// using the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, locale)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSwedishHolidays(t *testing.T) {
+	tests := []struct {
+		year int
+		name string
+		want string
+	}{
+		{2025, "Långfredagen", "2025-04-18"},
+		{2025, "Påskdagen", "2025-04-20"},
+		{2024, "Påskdagen", "2024-03-31"},
+		{2026, "Annandag påsk", "2026-04-06"},
+		{2025, "Kristi himmelsfärdsdag", "2025-05-29"},
+		{2025, "Pingstdagen", "2025-06-08"},
+		{2025, "Midsommarafton", "2025-06-20"},
+		{2025, "Midsommardagen", "2025-06-21"},
+		{2026, "Midsommardagen", "2026-06-20"},
+		{2025, "Alla helgons dag", "2025-11-01"},
+		{2026, "Alla helgons dag", "2026-10-31"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, h := range SwedishHolidays(tc.year) {
+				if h.Name == tc.name {
+					if got := h.Date.Format(time.DateOnly); got != tc.want {
+						t.Errorf("SwedishHolidays(%d) %s got = %s, want = %s", tc.year, tc.name, got, tc.want)
+					}
+					return
+				}
+			}
+			t.Errorf("SwedishHolidays(%d) is missing %s", tc.year, tc.name)
+		})
+	}
+}
+
+func TestIsSwedishHoliday(t *testing.T) {
+	tests := []struct {
+		date time.Time
+		want bool
+	}{
+		{time.Date(2025, 1, 6, 12, 0, 0, 0, locale), true},
+		{time.Date(2025, 1, 7, 12, 0, 0, 0, locale), false},
+		{time.Date(2025, 12, 24, 8, 0, 0, 0, locale), true},
+		{time.Date(2025, 4, 21, 8, 0, 0, 0, locale), true},
+		// 23:30 UTC is already Christmas Day in Stockholm.
+		{time.Date(2025, 12, 24, 23, 30, 0, 0, time.UTC), true},
+		{time.Date(2025, 12, 27, 23, 30, 0, 0, time.UTC), false},
+	}
+	for _, tc := range tests {
+		if got := IsSwedishHoliday(tc.date); got != tc.want {
+			t.Errorf("IsSwedishHoliday(%s) got = %v, want = %v", tc.date, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var influxV1Addr = flag.String("influxv1addr", "", "InfluxDB 1.x address, http(s)://host:8086 or udp://host:8089, disabled if empty")
+var influxV1DB = flag.String("influxv1db", "prices", "InfluxDB 1.x database")
+var influxV1RP = flag.String("influxv1rp", "", "InfluxDB 1.x retention policy, the database default if empty")
+var influxV1Username = flag.String("influxv1username", "", "InfluxDB 1.x username")
+var influxV1Password = flag.String("influxv1password", "", "InfluxDB 1.x password")
+var influxV1Precision = flag.String("influxv1precision", "s", "InfluxDB 1.x timestamp precision, one of: ns, u, ms, s")
+
+// maxUDPPayload keeps datagrams below the common ethernet MTU.
+const maxUDPPayload = 1400
+
+// influxV1Sink writes line protocol to the InfluxDB 1.x /write API, or as
+// fire-and-forget UDP datagrams if the address has the udp scheme.
+type influxV1Sink struct {
+	addr      *url.URL
+	db        string
+	rp        string
+	username  string
+	password  string
+	precision string
+	tariff    *Tariff
+	client    *http.Client
+}
+
+func newInfluxV1Sink(addr string, tariff *Tariff) (*influxV1Sink, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid InfluxDB 1.x address: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "udp" {
+		return nil, fmt.Errorf("invalid InfluxDB 1.x address %q, scheme must be http, https or udp", addr)
+	}
+	if *influxV1Precision == "us" {
+		return nil, fmt.Errorf("InfluxDB 1.x uses u for microsecond precision")
+	}
+	if _, err := parsePrecision(*influxV1Precision); err != nil {
+		return nil, err
+	}
+	return &influxV1Sink{
+		addr:      u,
+		db:        *influxV1DB,
+		rp:        *influxV1RP,
+		username:  *influxV1Username,
+		password:  *influxV1Password,
+		precision: *influxV1Precision,
+		tariff:    tariff,
+		client:    &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}, nil
+}
+
+func (s *influxV1Sink) Name() string {
+	return "influxdb-v1"
+}
+
+func (s *influxV1Sink) Write(ctx context.Context, samples []Sample) error {
+	precision, err := parsePrecision(s.precision)
+	if err != nil {
+		return err
+	}
+	if s.addr.Scheme == "udp" {
+		// The UDP listener of InfluxDB 1.x defaults to nanosecond timestamps.
+		return s.writeUDP(ctx, encodeLines(samples, s.tariff, time.Nanosecond))
+	}
+	body := encodeLines(samples, s.tariff, precision)
+	if len(body) == 0 {
+		return nil
+	}
+	return s.writeHTTP(ctx, body)
+}
+
+func (s *influxV1Sink) writeHTTP(ctx context.Context, body []byte) error {
+	u := s.addr.JoinPath("write")
+	q := u.Query()
+	q.Set("db", s.db)
+	if s.rp != "" {
+		q.Set("rp", s.rp)
+	}
+	q.Set("precision", s.precision)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("InfluxDB 1.x write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// writeUDP sends the lines in as few datagrams as possible without
+// splitting a line.
+func (s *influxV1Sink) writeUDP(ctx context.Context, body []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", s.addr.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var packet []byte
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		if len(packet)+len(line) > maxUDPPayload && len(packet) > 0 {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err = conn.Write(packet)
+	}
+	return err
+}
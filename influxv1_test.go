@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestInfluxV1SinkHTTP(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/write" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("db") != "prices" || q.Get("rp") != "autogen" || q.Get("precision") != "s" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Errorf("basic auth got = %q %q, want = user pass", user, pass)
+		}
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	addr, _ := url.Parse(ts.URL)
+	sink := &influxV1Sink{addr: addr, db: "prices", rp: "autogen", username: "user", password: "pass", precision: "s", client: ts.Client()}
+	samples := []Sample{{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)}}
+	if err := sink.Write(context.Background(), samples); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+	if want := "price,currency=SEK price=0.5 1738450800\n"; got != want {
+		t.Errorf("body got = %q, want = %q", got, want)
+	}
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database not found: \"prices\""}`, http.StatusNotFound)
+	})
+	if err := sink.Write(context.Background(), samples); err == nil {
+		t.Error("Write() error got = nil, want error")
+	}
+}
+
+func TestInfluxV1SinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	addr, _ := url.Parse("udp://" + conn.LocalAddr().String())
+	sink := &influxV1Sink{addr: addr, precision: "s"}
+	var samples []Sample
+	for i := 0; i < 40; i++ {
+		samples = append(samples, Sample{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)})
+	}
+	if err := sink.Write(context.Background(), samples); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+
+	// 40 lines of 60 bytes do not fit in one datagram.
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65536)
+	lines := 0
+	for datagrams := 0; lines < 40; datagrams++ {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("got %d lines in %d datagrams: %v", lines, datagrams, err)
+		}
+		if n > maxUDPPayload {
+			t.Errorf("datagram size got = %d, want <= %d", n, maxUDPPayload)
+		}
+		for _, b := range buf[:n] {
+			if b == '\n' {
+				lines++
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+var influxV3Addr = flag.String("influxv3addr", "", "InfluxDB 3 address, e.g. http://localhost:8181, disabled if empty")
+var influxV3Token = flag.String("influxv3token", "", "InfluxDB 3 token")
+var influxV3DB = flag.String("influxv3db", "prices", "InfluxDB 3 database")
+var influxV3Table = flag.String("influxv3table", "price", "InfluxDB 3 table the price measurement is written to")
+var influxV3Precision = flag.String("influxv3precision", "s", "InfluxDB 3 timestamp precision, one of: ns, us, ms, s")
+var influxV3AcceptPartial = flag.Bool("influxv3acceptpartial", true, "Let InfluxDB 3 write the valid lines of a batch with rejected lines")
+
+// influxV3PrecisionNames maps the line protocol precisions to the names of
+// the InfluxDB 3 write_lp API.
+var influxV3PrecisionNames = map[time.Duration]string{
+	time.Nanosecond:  "nanosecond",
+	time.Microsecond: "microsecond",
+	time.Millisecond: "millisecond",
+	time.Second:      "second",
+}
+
+// influxV3Sink writes line protocol to the InfluxDB 3 /api/v3/write_lp API.
+// The measurement becomes the table, and the currency and priceclass tags
+// and the price fields become its tag and field columns.
+type influxV3Sink struct {
+	addr          *url.URL
+	token         string
+	db            string
+	table         string
+	precision     time.Duration
+	acceptPartial bool
+	tariff        *Tariff
+	client        *http.Client
+}
+
+// influxV3LineError is a line rejected by InfluxDB 3.
+type influxV3LineError struct {
+	OriginalLine string `json:"original_line"`
+	LineNumber   int    `json:"line_number"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// influxV3Error is the body of a failed write_lp request.
+type influxV3Error struct {
+	Error string              `json:"error"`
+	Data  []influxV3LineError `json:"data"`
+}
+
+func newInfluxV3Sink(addr string, tariff *Tariff) (*influxV3Sink, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid InfluxDB 3 address: %v", err)
+	}
+	precision, err := parsePrecision(*influxV3Precision)
+	if err != nil {
+		return nil, err
+	}
+	return &influxV3Sink{
+		addr:          u,
+		token:         *influxV3Token,
+		db:            *influxV3DB,
+		table:         *influxV3Table,
+		precision:     precision,
+		acceptPartial: *influxV3AcceptPartial,
+		tariff:        tariff,
+		client:        &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}, nil
+}
+
+func (s *influxV3Sink) Name() string {
+	return "influxdb-v3"
+}
+
+func (s *influxV3Sink) Write(ctx context.Context, samples []Sample) error {
+	var body bytes.Buffer
+	for _, sample := range samples {
+		p := samplePoint(sample, s.tariff)
+		if p == nil {
+			continue
+		}
+		if s.table != p.Name() {
+			p = renamePoint(p, s.table)
+		}
+		body.WriteString(write.PointToLineProtocol(p, s.precision))
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	u := s.addr.JoinPath("api", "v3", "write_lp")
+	q := u.Query()
+	q.Set("db", s.db)
+	q.Set("precision", influxV3PrecisionNames[s.precision])
+	q.Set("accept_partial", strconv.FormatBool(s.acceptPartial))
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var writeErr influxV3Error
+	if err := json.Unmarshal(msg, &writeErr); err != nil || len(writeErr.Data) == 0 {
+		return fmt.Errorf("InfluxDB 3 write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	for _, line := range writeErr.Data {
+		slog.Warn("InfluxDB 3 rejected line", "sink", s.Name(), "line", line.LineNumber, "original", line.OriginalLine, "error", line.ErrorMessage)
+	}
+	return fmt.Errorf("InfluxDB 3 write returned %s: %s, %d of %d lines rejected", resp.Status, writeErr.Error, len(writeErr.Data), len(samples))
+}
+
+// renamePoint returns a copy of the point with another measurement name.
+func renamePoint(p *write.Point, name string) *write.Point {
+	renamed := write.NewPointWithMeasurement(name).SetTime(p.Time())
+	for _, tag := range p.TagList() {
+		renamed.AddTag(tag.Key, tag.Value)
+	}
+	for _, field := range p.FieldList() {
+		renamed.AddField(field.Key, field.Value)
+	}
+	return renamed
+}
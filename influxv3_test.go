@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxV3Sink(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/write_lp" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		q := r.URL.Query()
+		if q.Get("db") != "prices" || q.Get("precision") != "second" || q.Get("accept_partial") != "true" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Authorization got = %q, want = Bearer secret", auth)
+		}
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	addr, _ := url.Parse(ts.URL)
+	sink := &influxV3Sink{addr: addr, token: "secret", db: "prices", table: "spot", precision: time.Second, acceptPartial: true, client: ts.Client()}
+	samples := []Sample{{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)}}
+	if err := sink.Write(context.Background(), samples); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+	if want := "spot,currency=SEK price=0.5 1738450800\n"; got != want {
+		t.Errorf("body got = %q, want = %q", got, want)
+	}
+}
+
+func TestInfluxV3SinkPartialWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"partial write of line protocol occurred","data":[{"original_line":"price,currency=SEK,priceclass=SE4 price=0.5 1738450800","line_number":2,"error_message":"invalid column type for column 'price', expected iox::column_type::field::integer, got iox::column_type::field::float"}]}`)
+	}))
+	defer ts.Close()
+
+	addr, _ := url.Parse(ts.URL)
+	sink := &influxV3Sink{addr: addr, db: "prices", table: "price", precision: time.Second, client: ts.Client()}
+	samples := []Sample{
+		{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)},
+		{Zone: "SE4", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)},
+	}
+	err := sink.Write(context.Background(), samples)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 lines rejected") {
+		t.Errorf("Write() error got = %v, want 1 of 2 lines rejected", err)
+	}
+}
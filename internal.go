@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var internalInflux = flag.Bool("internal-metrics", false, "Write the self-instrumentation metrics to InfluxDB as the price2influx_internal measurement every -influxupdaterate")
+
+var (
+	fetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "price2influx_fetch_duration_seconds",
+		Help: "Latency of upstream price fetches by provider and HTTP status code, error if no response.",
+	}, []string{"provider", "code"})
+	parseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "price2influx_parse_failures_total",
+		Help: "Upstream responses that could not be parsed.",
+	}, []string{"provider"})
+	writeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "price2influx_sink_write_duration_seconds",
+		Help: "Latency of writes to a sink.",
+	}, []string{"sink"})
+	writeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "price2influx_sink_write_errors_total",
+		Help: "Failed writes to a sink.",
+	}, []string{"sink"})
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "price2influx_sink_queue_depth",
+		Help: "Failed batches of a sink waiting to be retried.",
+	}, []string{"sink"})
+	inflight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "price2influx_inflight",
+		Help: "Upstream fetches and sink writes in flight.",
+	}, []string{"op"})
+	goroutines = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "price2influx_goroutines",
+		Help: "Goroutines of the process.",
+	}, func() float64 { return float64(runtime.NumGoroutine()) })
+)
+
+// internalMetrics holds the self-instrumentation metrics, served on
+// /metrics and written by InternalMetricsWriter.
+var internalMetrics = prometheus.NewRegistry()
+
+func init() {
+	internalMetrics.MustRegister(fetchDuration, parseFailures, writeDuration, writeErrors, queueDepth, inflight, goroutines)
+}
+
+// internalPoints returns the metrics of g as price2influx_internal points,
+// one per label set with the labels as tags. Histograms are written as
+// their _count and _sum.
+func internalPoints(g prometheus.Gatherer, ts time.Time) ([]*write.Point, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+	points := map[string]*write.Point{}
+	var keys []string
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			key := ""
+			for _, l := range m.GetLabel() {
+				key += l.GetName() + "=" + l.GetValue() + ","
+			}
+			p, ok := points[key]
+			if !ok {
+				p = write.NewPoint("price2influx_internal", nil, nil, ts)
+				for _, l := range m.GetLabel() {
+					p.AddTag(l.GetName(), l.GetValue())
+				}
+				points[key] = p
+				keys = append(keys, key)
+			}
+			name := family.GetName()
+			switch {
+			case m.Counter != nil:
+				p.AddField(name, m.GetCounter().GetValue())
+			case m.Gauge != nil:
+				p.AddField(name, m.GetGauge().GetValue())
+			case m.Histogram != nil:
+				p.AddField(name+"_count", m.GetHistogram().GetSampleCount())
+				p.AddField(name+"_sum", m.GetHistogram().GetSampleSum())
+			}
+		}
+	}
+	sort.Strings(keys)
+	res := make([]*write.Point, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, points[key])
+	}
+	return res, nil
+}
+
+// InternalMetricsWriter writes the self-instrumentation metrics to InfluxDB
+// every interval until ctx is done.
+func InternalMetricsWriter(ctx context.Context, writeAPI api.WriteAPIBlocking, interval time.Duration) {
+	for sleep(ctx, interval) {
+		points, err := internalPoints(internalMetrics, time.Now())
+		if err != nil {
+			slog.Error("Gathering internal metrics failed", "error", err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, interval)
+		err = writeAPI.WritePoint(ctx, points...)
+		cancel()
+		if err != nil {
+			slog.Error("Write internal metrics to influx failed", "error", err)
+		}
+	}
+}
+
+// observeFetch records an upstream fetch that started at start and got the
+// HTTP status code, 0 if there was no response.
+func observeFetch(start time.Time, code int) {
+	label := "error"
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+	fetchDuration.WithLabelValues(Provider, label).Observe(time.Since(start).Seconds())
+}
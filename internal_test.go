@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentation(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprintln(w, day1)
+		case "/broken":
+			fmt.Fprintln(w, "{")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+	pc := &PriceClient{priceClass: "SE3", baseURL: upstream.URL, client: upstream.Client()}
+
+	parseBefore := testutil.ToFloat64(parseFailures.WithLabelValues(Provider))
+	for _, path := range []string{"/ok", "/broken", "/missing"} {
+		pc.fetchPrices(context.Background(), upstream.URL+path)
+	}
+	if got := testutil.ToFloat64(parseFailures.WithLabelValues(Provider)) - parseBefore; got != 1 {
+		t.Errorf("parse failures got = %v, want = 1", got)
+	}
+	// One series for the 200 responses and one for the 404.
+	if got := testutil.CollectAndCount(fetchDuration); got < 2 {
+		t.Errorf("fetch duration series got = %d, want >= 2", got)
+	}
+
+	sink := &recordSink{err: errors.New("unavailable")}
+	route := newSinkRoute(sink)
+	route.name = "instrumented"
+	route.onFailure = "retry"
+	samples := []Sample{{Zone: "SE3", Price: Price{SEKPerkWh: 1}, Time: time.Now()}}
+	writeBefore := testutil.ToFloat64(writeErrors.WithLabelValues("instrumented"))
+	route.write(samples)
+	route.write(samples)
+	if got := testutil.ToFloat64(writeErrors.WithLabelValues("instrumented")) - writeBefore; got != 2 {
+		t.Errorf("write errors got = %v, want = 2", got)
+	}
+	if got := testutil.ToFloat64(queueDepth.WithLabelValues("instrumented")); got != 2 {
+		t.Errorf("queue depth got = %v, want = 2", got)
+	}
+	sink.err = nil
+	route.write(samples)
+	if got := testutil.ToFloat64(queueDepth.WithLabelValues("instrumented")); got != 0 {
+		t.Errorf("queue depth after recovery got = %v, want = 0", got)
+	}
+}
+
+func TestInternalPoints(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_errors_total"}, []string{"sink"})
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_duration_seconds"}, []string{"sink"})
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_inflight"}, []string{"op"})
+	reg.MustRegister(counter, histogram, gauge)
+	counter.WithLabelValues("influxdb").Add(3)
+	histogram.WithLabelValues("influxdb").Observe(0.5)
+	histogram.WithLabelValues("influxdb").Observe(1.5)
+	histogram.WithLabelValues("mqtt").Observe(0.25)
+	gauge.WithLabelValues("write").Set(2)
+
+	ts := time.Date(2025, 2, 2, 10, 30, 0, 0, locale)
+	points, err := internalPoints(reg, ts)
+	if err != nil {
+		t.Fatalf("internalPoints() error = %v", err)
+	}
+	var got []string
+	for _, p := range points {
+		got = append(got, write.PointToLineProtocol(p, time.Second))
+	}
+	want := []string{
+		"price2influx_internal,op=write test_inflight=2 1738488600\n",
+		"price2influx_internal,sink=influxdb test_duration_seconds_count=2u,test_duration_seconds_sum=2,test_errors_total=3 1738488600\n",
+		"price2influx_internal,sink=mqtt test_duration_seconds_count=1u,test_duration_seconds_sum=0.25 1738488600\n",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("internalPoints() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var logFormat = flag.String("log-format", "text", "Log output format, text or json")
+var logLevelFlag = flag.String("log-level", "info", "Log level, one of debug, info, warn or error, changeable at runtime on the admin API")
+var logRepeatInterval = flag.Duration("log-repeat-interval", 5*time.Minute, "Interval identical warnings and errors are logged at most once per, 0 logs all")
+
+// logLevel is the runtime level of the default logger.
+var logLevel = new(slog.LevelVar)
+
+// maxRepeats bounds the remembered records of a repeatHandler, older records
+// are forgotten beyond it.
+const maxRepeats = 1024
+
+// setupLogging makes the default logger write to w in the -log-format at
+// logLevel, rate limiting repeated warnings and errors. The log package
+// writes through it as well.
+func setupLogging(w io.Writer) error {
+	if err := logLevel.UnmarshalText([]byte(*logLevelFlag)); err != nil {
+		return fmt.Errorf("invalid log-level %q: %v", *logLevelFlag, err)
+	}
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler
+	switch *logFormat {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("log-format must be text or json")
+	}
+	if *logRepeatInterval > 0 {
+		h = newRepeatHandler(h, *logRepeatInterval)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// repeat is a record seen by a repeatHandler.
+type repeat struct {
+	last       time.Time
+	suppressed int
+}
+
+// repeatHandler passes identical warnings and errors, same level, message
+// and attributes, at most once per interval to the next handler. The next
+// record passed carries the number suppressed in between as repeated.
+type repeatHandler struct {
+	next     slog.Handler
+	interval time.Duration
+	// attrs are the attributes added with WithAttrs, part of the identity.
+	attrs string
+
+	mu      *sync.Mutex
+	repeats map[string]*repeat
+}
+
+func newRepeatHandler(next slog.Handler, interval time.Duration) *repeatHandler {
+	return &repeatHandler{next: next, interval: interval, mu: &sync.Mutex{}, repeats: map[string]*repeat{}}
+}
+
+func (h *repeatHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *repeatHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return h.next.Handle(ctx, r)
+	}
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s %s%s", r.Level, r.Message, h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&key, " %s", a)
+		return true
+	})
+
+	h.mu.Lock()
+	rep, ok := h.repeats[key.String()]
+	if ok && r.Time.Sub(rep.last) < h.interval {
+		rep.suppressed++
+		h.mu.Unlock()
+		return nil
+	}
+	if !ok {
+		if len(h.repeats) >= maxRepeats {
+			for k, old := range h.repeats {
+				if r.Time.Sub(old.last) >= h.interval {
+					delete(h.repeats, k)
+				}
+			}
+		}
+		if len(h.repeats) >= maxRepeats {
+			h.mu.Unlock()
+			return h.next.Handle(ctx, r)
+		}
+		rep = &repeat{}
+		h.repeats[key.String()] = rep
+	}
+	suppressed := rep.suppressed
+	rep.last, rep.suppressed = r.Time, 0
+	h.mu.Unlock()
+
+	if suppressed > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("repeated", suppressed))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *repeatHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	for _, a := range attrs {
+		c.attrs += " " + a.String()
+	}
+	return &c
+}
+
+func (h *repeatHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.attrs += " " + name + ":"
+	return &c
+}
+
+// logger returns the logger of the client's zone.
+func (p *PriceClient) logger() *slog.Logger {
+	return slog.With("zone", p.priceClass, "provider", Provider)
+}
+
+// logger returns the logger of the route's sink.
+func (r *sinkRoute) logger() *slog.Logger {
+	return slog.With("sink", r.name)
+}
+
+// logger returns the logger of the sink, the same as of its route.
+func (s *mqttSink) logger() *slog.Logger {
+	return slog.With("sink", s.name)
+}
+
+// fatal logs v at error level and exits.
+func fatal(v any) {
+	slog.Error(fmt.Sprint(v))
+	os.Exit(1)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRepeatHandler(t *testing.T) {
+	var buf bytes.Buffer
+	text := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	h := newRepeatHandler(text, time.Minute)
+	se3 := h.WithAttrs([]slog.Attr{slog.String("zone", "SE3")})
+	se4 := h.WithAttrs([]slog.Attr{slog.String("zone", "SE4")})
+	start := time.Date(2025, 2, 2, 10, 30, 0, 0, locale)
+
+	logs := []struct {
+		h     slog.Handler
+		at    time.Duration
+		level slog.Level
+		msg   string
+	}{
+		{h: se3, level: slog.LevelWarn, msg: "No current price"},
+		{h: se3, at: 10 * time.Second, level: slog.LevelWarn, msg: "No current price"},
+		{h: se4, at: 10 * time.Second, level: slog.LevelWarn, msg: "No current price"},
+		{h: se3, at: 20 * time.Second, level: slog.LevelInfo, msg: "Prices loaded"},
+		{h: se3, at: 20 * time.Second, level: slog.LevelInfo, msg: "Prices loaded"},
+		{h: se3, at: 30 * time.Second, level: slog.LevelWarn, msg: "No current price"},
+		{h: se3, at: 70 * time.Second, level: slog.LevelWarn, msg: "No current price"},
+		{h: se3, at: 80 * time.Second, level: slog.LevelWarn, msg: "No current price"},
+	}
+	for _, l := range logs {
+		if err := l.h.Handle(context.Background(), slog.NewRecord(start.Add(l.at), l.level, l.msg, 0)); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	want := []string{
+		`level=WARN msg="No current price" zone=SE3`,
+		`level=WARN msg="No current price" zone=SE4`,
+		`level=INFO msg="Prices loaded" zone=SE3`,
+		`level=INFO msg="Prices loaded" zone=SE3`,
+		`level=WARN msg="No current price" zone=SE3 repeated=2`,
+	}
+	if diff := cmp.Diff(want, strings.Split(strings.TrimSpace(buf.String()), "\n")); diff != "" {
+		t.Errorf("log output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer func(format, level string) { *logFormat, *logLevelFlag = format, level }(*logFormat, *logLevelFlag)
+	tests := []struct {
+		name    string
+		format  string
+		level   string
+		wantErr bool
+		want    string
+	}{
+		{name: "json", format: "json", level: "warn", want: `"msg":"shown"`},
+		{name: "text", format: "text", level: "debug", want: `msg=hidden`},
+		{name: "bad format", format: "xml", level: "info", wantErr: true},
+		{name: "bad level", format: "text", level: "loud", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*logFormat, *logLevelFlag = tt.format, tt.level
+			var buf bytes.Buffer
+			err := setupLogging(&buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setupLogging() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			slog.Info("hidden")
+			slog.Warn("shown")
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("log output got = %s, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+	logLevel.Set(slog.LevelInfo)
+}
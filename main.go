@@ -2,17 +2,27 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var priceClasses = []string{"SE1", "SE2", "SE3", "SE4"}
@@ -22,7 +32,10 @@ var influxToken = flag.String("influxtoken", "my-token", "InfluxDB token")
 var influxInterval = flag.Duration("influxupdaterate", time.Second*10, "InfluxDB datapoint injection rate")
 var influxOrg = flag.String("influxorg", "my-org", "InfluxDB Organisation")
 var influxBucket = flag.String("influxbucket", "my-bucket", "InfluxDB bucket")
-var priceClass = flag.String("priceclass", "SE3", fmt.Sprintf("Priceclass, one of: %v", priceClasses))
+var priceClass = flag.String("priceclass", "SE3", fmt.Sprintf("Priceclasses, comma separated, any of: %v", priceClasses))
+var priceClassTag = flag.Bool("priceclass-tag", false, "Tag price points with the priceclass, always on if several priceclasses are given. Starts new series for existing single zone setups")
+var influxEnabled = flag.Bool("influx", true, "Push prices to InfluxDB")
+var httpAddr = flag.String("httpaddr", "", "Address for the HTTP API to listen on, disabled if empty")
 
 var clockSourceNow = time.Now
 var locale *time.Location
@@ -30,8 +43,17 @@ var locale *time.Location
 const (
 	Locale  = "Europe/Stockholm"
 	BaseURL = "https://www.elprisetjustnu.se"
+	// Provider names the source of the prices in the outputs.
+	Provider = "elprisetjustnu"
+
+	// TomorrowPublishHour is the local hour from which the next day's prices
+	// are expected to be available from the API.
+	TomorrowPublishHour = 13
 )
 
+var errNotPublished = errors.New("prices not published yet")
+var errNotLoadable = errors.New("only today's and tomorrow's prices are loaded")
+
 type Price struct {
 	SEKPerkWh float64   `json:"SEK_per_kWh"`
 	EURPerkWh float64   `json:"EUR_per_kWh"`
@@ -42,11 +64,73 @@ type Price struct {
 
 type Prices []Price
 
+var currencies = []string{"SEK", "EUR"}
+
+// PerkWh returns the price in the given currency, one of currencies.
+func (p Price) PerkWh(currency string) float64 {
+	if currency == "EUR" {
+		return p.EURPerkWh
+	}
+	return p.SEKPerkWh
+}
+
+// Stats summarizes a set of prices in one currency.
+type Stats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// Stats returns the min, max and time weighted average price in currency.
+func (ps Prices) Stats(currency string) Stats {
+	if len(ps) == 0 {
+		return Stats{}
+	}
+	s := Stats{Min: ps[0].PerkWh(currency), Max: ps[0].PerkWh(currency)}
+	var total time.Duration
+	for _, p := range ps {
+		v := p.PerkWh(currency)
+		s.Min = min(s.Min, v)
+		s.Max = max(s.Max, v)
+		s.Avg += v * p.TimeEnd.Sub(p.TimeStart).Hours()
+		total += p.TimeEnd.Sub(p.TimeStart)
+	}
+	if total > 0 {
+		s.Avg /= total.Hours()
+	}
+	return s
+}
+
+// Level classifies the price relative to the average price of ps, from
+// very_cheap to very_expensive.
+func (ps Prices) Level(p Price) string {
+	avg := ps.Stats("SEK").Avg
+	if avg <= 0 {
+		// Ratios are meaningless without a positive average.
+		if p.SEKPerkWh > avg {
+			return "expensive"
+		}
+		return "cheap"
+	}
+	switch ratio := p.SEKPerkWh / avg; {
+	case ratio < 0.6:
+		return "very_cheap"
+	case ratio < 0.9:
+		return "cheap"
+	case ratio < 1.15:
+		return "normal"
+	case ratio < 1.4:
+		return "expensive"
+	default:
+		return "very_expensive"
+	}
+}
+
 func NewPriceClient(priceclass string) *PriceClient {
 	return &PriceClient{
 		baseURL:    BaseURL,
 		priceClass: priceclass,
-		client:     &http.Client{Timeout: 10 * time.Second},
+		client:     &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
 	}
 }
 
@@ -55,131 +139,643 @@ type PriceClient struct {
 	priceClass string
 	client     *http.Client
 
-	mu     sync.Mutex
-	prices Prices
+	mu       sync.Mutex
+	prices   Prices
+	tomorrow Prices
+	loadedAt time.Time
+	// fetchErr is the last failed fetch of today's or tomorrow's prices.
+	fetchErr     error
+	fetchErrAt   time.Time
+	nextRefresh  time.Time
+	nextTomorrow time.Time
+
+	// past caches the fetched days before today, see PricesFor.
+	past dayCache
 }
 
-func (p *PriceClient) apiURL() string {
-	now := clockSourceNow()
+// PriceClients holds one PriceClient per configured priceclass.
+type PriceClients []*PriceClient
+
+// Zone returns the client for the priceclass, or the first client if zone
+// is empty. nil is returned for unknown priceclasses.
+func (pcs PriceClients) Zone(zone string) *PriceClient {
+	for _, pc := range pcs {
+		if zone == "" || pc.priceClass == zone {
+			return pc
+		}
+	}
+	return nil
+}
+
+// Zone returns the priceclass of the client.
+func (p *PriceClient) Zone() string {
+	return p.priceClass
+}
+
+func (p *PriceClient) apiURLFor(date time.Time) string {
 	return fmt.Sprintf("%s/api/v1/prices/%d/%s_%s.json",
 		p.baseURL,
-		now.In(locale).Year(),
-		now.In(locale).Format("01-02"),
+		date.In(locale).Year(),
+		date.In(locale).Format("01-02"),
 		p.priceClass,
 	)
 }
 
-// CurrentPriceSEK returns the price in SEK at this given time.
-func (p *PriceClient) CurrentPriceSEK() (float64, error) {
-	p.mu.Lock()
+// CurrentPrice returns the price interval active at this given time.
+func (p *PriceClient) CurrentPrice(ctx context.Context) (Price, error) {
+	p.lockTraced(ctx)
 	defer p.mu.Unlock()
 	now := clockSourceNow()
 	for _, price := range p.prices {
 		if price.TimeStart.Before(now) && price.TimeEnd.After(now) {
-			return price.SEKPerkWh, nil
+			return price, nil
 		}
 	}
-	return 0, fmt.Errorf("no current price found, no fresh data?")
+	return Price{}, fmt.Errorf("no current price found, no fresh data?")
 }
 
-// LoadPrices loads the prices for the active day into memory.
-func (p *PriceClient) LoadPrices() error {
-	resp, err := p.client.Get(p.apiURL())
+// NextPrice returns the price interval following the active one.
+func (p *PriceClient) NextPrice() (Price, error) {
+	now := clockSourceNow()
+	for _, price := range p.Schedule(context.Background()) {
+		if price.TimeStart.After(now) {
+			return price, nil
+		}
+	}
+	return Price{}, fmt.Errorf("no next price found, tomorrow not published yet?")
+}
+
+// Today returns the prices loaded for the active day.
+func (p *PriceClient) Today() Prices {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append(Prices(nil), p.prices...)
+}
+
+// LoadedAt returns when today's prices were last loaded.
+func (p *PriceClient) LoadedAt() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadedAt
+}
+
+// CurrentPriceSEK returns the price in SEK at this given time.
+func (p *PriceClient) CurrentPriceSEK() (float64, error) {
+	price, err := p.CurrentPrice(context.Background())
+	return price.SEKPerkWh, err
+}
+
+// Schedule returns all loaded prices, today followed by tomorrow if published.
+func (p *PriceClient) Schedule(ctx context.Context) Prices {
+	p.lockTraced(ctx)
+	defer p.mu.Unlock()
+	schedule := make(Prices, 0, len(p.prices)+len(p.tomorrow))
+	schedule = append(schedule, p.prices...)
+	return append(schedule, p.tomorrow...)
+}
+
+// fetchPrices fetches and parses the prices at url of the API.
+func (p *PriceClient) fetchPrices(ctx context.Context, url string) (prices Prices, err error) {
+	ctx, span := tracer.Start(ctx, "fetchPrices", trace.WithAttributes(zoneKey.String(p.priceClass), providerKey.String(Provider)))
+	defer func() { endSpan(span, err) }()
+	inflight.WithLabelValues("fetch").Inc()
+	defer inflight.WithLabelValues("fetch").Dec()
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("error reading from %s: %v", BaseURL, err)
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		observeFetch(start, 0)
+		return nil, fmt.Errorf("error reading from %s: %v", BaseURL, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		observeFetch(start, resp.StatusCode)
+		return nil, errNotPublished
+	}
 	body, err := io.ReadAll(resp.Body)
+	observeFetch(start, resp.StatusCode)
 	if err != nil {
-		return fmt.Errorf("error reading response: %v", err)
+		return nil, fmt.Errorf("error reading response: %v", err)
 	}
-	var prices Prices
+	_, parse := tracer.Start(ctx, "parsePrices")
 	err = json.Unmarshal(body, &prices)
+	endSpan(parse, err)
 	if err != nil {
-		return fmt.Errorf("error parsing json: %v", err)
+		parseFailures.WithLabelValues(Provider).Inc()
+		return nil, fmt.Errorf("error parsing json: %v", err)
 	}
+	return prices, nil
+}
+
+// fetchFailed records err as the last fetch error.
+func (p *PriceClient) fetchFailed(err error) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.fetchErr = err
+	p.fetchErrAt = clockSourceNow()
+	return err
+}
+
+// lockTraced locks the client in a span, showing lock contention in traces.
+// Outside a trace the lock is not traced, so readers like the metrics
+// collector do not start traces of their own.
+func (p *PriceClient) lockTraced(ctx context.Context) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		p.mu.Lock()
+		return
+	}
+	_, span := tracer.Start(ctx, "PriceClient.lock")
+	p.mu.Lock()
+	span.End()
+}
+
+// LoadPrices loads the prices for the active day into memory.
+func (p *PriceClient) LoadPrices() (err error) {
+	now := clockSourceNow()
+	ctx, span := tracer.Start(context.Background(), "LoadPrices",
+		trace.WithAttributes(zoneKey.String(p.priceClass), dateAttr(now), providerKey.String(Provider)))
+	defer func() { endSpan(span, err) }()
+	prices, err := p.fetchPrices(ctx, p.apiURLFor(now))
+	if err != nil {
+		return p.fetchFailed(err)
+	}
+	p.lockTraced(ctx)
+	defer p.mu.Unlock()
 	p.prices = prices
-	fmt.Println("Prices loaded from", BaseURL)
+	p.loadedAt = clockSourceNow()
+	// Drop tomorrow's prices once they have become today's.
+	if len(p.tomorrow) > 0 && len(prices) > 0 && !p.tomorrow[0].TimeStart.After(prices[len(prices)-1].TimeStart) {
+		p.tomorrow = nil
+	}
+	p.logger().Info("Prices loaded", "date", now.In(locale).Format(time.DateOnly), "intervals", len(prices))
 	return nil
 }
 
-// PriceLoader handles the refresh of prices from the API at midnight.
-func (p *PriceClient) PriceLoader() {
+// LoadTomorrowPrices loads the prices for the next day into memory.
+// errNotPublished is returned if the prices are not available yet.
+func (p *PriceClient) LoadTomorrowPrices() (err error) {
+	tomorrow := clockSourceNow().In(locale).AddDate(0, 0, 1)
+	ctx, span := tracer.Start(context.Background(), "LoadTomorrowPrices",
+		trace.WithAttributes(zoneKey.String(p.priceClass), dateAttr(tomorrow), providerKey.String(Provider)))
+	defer func() { endSpan(span, err) }()
+	prices, err := p.fetchPrices(ctx, p.apiURLFor(tomorrow))
+	if errors.Is(err, errNotPublished) {
+		return err
+	}
+	if err != nil {
+		return p.fetchFailed(err)
+	}
+	p.lockTraced(ctx)
+	defer p.mu.Unlock()
+	p.tomorrow = prices
+	p.logger().Info("Tomorrow's prices loaded", "date", tomorrow.Format(time.DateOnly), "intervals", len(prices))
+	return nil
+}
+
+// Reload fetches the prices of the local day of date again, replacing the
+// loaded prices of today or tomorrow. errNotLoadable is returned for other
+// days, which are not kept in memory.
+func (p *PriceClient) Reload(date time.Time) (Prices, error) {
+	year, month, day := clockSourceNow().In(locale).Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, locale)
+	switch date.In(locale).Format(time.DateOnly) {
+	case today.Format(time.DateOnly):
+		if err := p.LoadPrices(); err != nil {
+			return nil, err
+		}
+		return p.Today(), nil
+	case today.AddDate(0, 0, 1).Format(time.DateOnly):
+		if err := p.LoadTomorrowPrices(); err != nil {
+			return nil, err
+		}
+		prices, _ := p.LoadedFor(date)
+		return prices, nil
+	}
+	return nil, errNotLoadable
+}
+
+// maxPastDays bounds the past days cached by a PriceClient.
+const maxPastDays = 400
+
+// PricesFor returns the prices of the local day of date, from memory for
+// today and tomorrow and from the API for past days, which are cached as
+// they do not change. Tomorrow is not fetched before TomorrowLoader has
+// loaded it and later days are never published, errNotPublished is
+// returned for them without asking the API.
+func (p *PriceClient) PricesFor(date time.Time) (Prices, error) {
+	if prices, ok := p.LoadedFor(date); ok {
+		return prices, nil
+	}
+	year, month, day := clockSourceNow().In(locale).Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, locale)
+	if !date.Before(today.AddDate(0, 0, 1)) {
+		return nil, errNotPublished
+	}
+	fetch := func() (Prices, error) {
+		ctx, span := tracer.Start(context.Background(), "PricesFor",
+			trace.WithAttributes(zoneKey.String(p.priceClass), dateAttr(date), providerKey.String(Provider)))
+		prices, err := p.fetchPrices(ctx, p.apiURLFor(date))
+		endSpan(span, err)
+		return prices, err
+	}
+	if !date.Before(today) {
+		return fetch()
+	}
+	return p.past.get(date.In(locale).Format(time.DateOnly), fetch)
+}
+
+// LoadedFor returns the prices of the local day of date if they are loaded.
+func (p *PriceClient) LoadedFor(date time.Time) (Prices, bool) {
+	day := date.In(locale).Format(time.DateOnly)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, loaded := range []Prices{p.prices, p.tomorrow} {
+		if len(loaded) > 0 && loaded[0].TimeStart.In(locale).Format(time.DateOnly) == day {
+			return append(Prices(nil), loaded...), true
+		}
+	}
+	return nil, false
+}
+
+// PriceLoader handles the refresh of prices from the API at midnight until
+// ctx is done.
+func (p *PriceClient) PriceLoader(ctx context.Context) {
 	for {
 		now := clockSourceNow()
 		year, month, day := now.In(locale).Date()
 		t := time.Date(year, month, day, 0, 0, 0, 0, locale).AddDate(0, 0, 1).Add(time.Second)
-		fmt.Println("Time for price refresh:", t)
-		<-time.After(time.Until(t))
-		fmt.Println("Fetching new prices from the API")
+		p.mu.Lock()
+		p.nextRefresh = t
+		p.mu.Unlock()
+		p.logger().Debug("Next price refresh scheduled", "at", t)
+		if !sleep(ctx, time.Until(t)) {
+			return
+		}
 		err := p.LoadPrices()
 		if err != nil {
-			fmt.Println("Error loading prices", err)
+			p.logger().Error("Loading prices failed", "error", err)
 			continue
 		}
 	}
 }
 
+// scheduleTomorrow records and waits until the next fetch of tomorrow's
+// prices at t, reporting false if ctx is done first.
+func (p *PriceClient) scheduleTomorrow(ctx context.Context, t time.Time) bool {
+	p.mu.Lock()
+	p.nextTomorrow = t
+	p.mu.Unlock()
+	return sleep(ctx, time.Until(t))
+}
+
+// TomorrowLoader fetches the next day's prices once they are published,
+// retrying every 15 minutes until they are available, until ctx is done.
+func (p *PriceClient) TomorrowLoader(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := clockSourceNow().In(locale)
+		year, month, day := now.Date()
+		publish := time.Date(year, month, day, TomorrowPublishHour, 0, 0, 0, locale)
+		tomorrow := time.Date(year, month, day+1, 0, 0, 0, 0, locale)
+		p.mu.Lock()
+		loaded := len(p.tomorrow) > 0 && !p.tomorrow[0].TimeStart.Before(tomorrow)
+		p.mu.Unlock()
+		switch {
+		case loaded:
+			p.scheduleTomorrow(ctx, publish.AddDate(0, 0, 1))
+			continue
+		case now.Before(publish):
+			if !p.scheduleTomorrow(ctx, publish) {
+				return
+			}
+		}
+		err := p.LoadTomorrowPrices()
+		if err != nil {
+			p.logger().Warn("Loading tomorrow's prices failed, retrying in 15 minutes", "error", err)
+			p.scheduleTomorrow(ctx, time.Now().Add(15*time.Minute))
+		}
+	}
+}
+
+// sleep waits for d, reporting false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
 func init() {
 	loc, err := time.LoadLocation(Locale)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	locale = loc
 }
 
+// parseZones parses a comma separated list of priceclasses, dropping
+// duplicates.
+func parseZones(s string) ([]string, error) {
+	var zones []string
+	for _, zone := range strings.Split(s, ",") {
+		zone = strings.TrimSpace(zone)
+		if !slices.Contains(priceClasses, zone) {
+			return nil, fmt.Errorf("priceclass %q must be one of %v", zone, priceClasses)
+		}
+		if !slices.Contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		if err := runPlan(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		if err := runHistory(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
 	flag.Parse()
-	if !slices.Contains(priceClasses, *priceClass) {
-		log.Fatalf("Priceclass must be one of %v", priceClasses)
+	if err := setupLogging(os.Stderr); err != nil {
+		fatal(err)
+	}
+	// The goroutines stop on SIGINT and SIGTERM, the sinks are closed once
+	// they have.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	zones, err := parseZones(*priceClass)
+	if err != nil {
+		fatal(err)
+	}
+	if len(zones) > 1 {
+		*priceClassTag = true
+	}
+
+	var tariff *Tariff
+	if *tariffFile != "" {
+		var err error
+		tariff, err = loadTariff(*tariffFile)
+		if err != nil {
+			fatal(err)
+		}
 	}
 
 	wg := sync.WaitGroup{}
-	priceClient := NewPriceClient(*priceClass)
+	var priceClients PriceClients
+	for _, zone := range zones {
+		priceClient := NewPriceClient(zone)
+		priceClients = append(priceClients, priceClient)
+
+		// Load the prices once
+		err := priceClient.LoadPrices()
+		if err != nil {
+			fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			priceClient.PriceLoader(ctx)
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			priceClient.TomorrowLoader(ctx)
+		}()
+	}
+
+	var history *HistoryStore
+	if *historyPath != "" {
+		var err error
+		history, err = openHistory(*historyPath)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if *otlpEndpoint != "" {
+		var err error
+		shutdownTracing, err = setupTracing(context.Background())
+		if err != nil {
+			fatal(err)
+		}
+	}
 
-	// Load the prices once
-	err := priceClient.LoadPrices()
+	auth, err := newAuthenticator()
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		priceClient.PriceLoader()
-	}()
-
-	client := influxdb2.NewClient(*influxAddr, *influxToken)
-	writeAPI := client.WriteAPIBlocking(*influxOrg, *influxBucket)
-	ticker := time.NewTicker(*influxInterval)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		for {
-			<-ticker.C
+		fatal(err)
+	}
+	var tlsConfig *tls.Config
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err = newTLSConfig(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Serving gRPC API", "addr", *grpcAddr)
+			opts := auth.grpcOptions()
+			if tlsConfig != nil {
+				opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			}
+			srv := newGRPCServer(priceClients, opts...)
 			go func() {
-				ctx, cancel := context.WithTimeout(context.Background(), *influxInterval-(time.Millisecond*500))
-				defer cancel()
+				<-ctx.Done()
+				srv.Stop()
+			}()
+			if err := srv.Serve(lis); err != nil {
+				fatal(err)
+			}
+		}()
+	}
 
-				price, err := priceClient.CurrentPriceSEK()
-				if err != nil {
-					log.Printf("GetCurrentPrice: %v", err)
-					return
-				}
-				p := influxdb2.NewPointWithMeasurement("price").
-					AddTag("currency", "SEK").
-					AddField("price", price).
-					SetTime(time.Now())
-				err = writeAPI.WritePoint(ctx, p)
-				if err != nil {
-					log.Printf("Write to influx failed: %v", err)
+	var sinks []Sink
+	if *stdoutMode {
+		sink := &lineSink{w: os.Stdout, tariff: tariff}
+		switch *stdoutSignal {
+		case "none":
+			sinks = append(sinks, sink)
+		case "stdin":
+			go func() {
+				if err := stdinSignal(os.Stdin, priceClients, sink); err != nil {
+					fatal(err)
 				}
+				// Telegraf closes stdin when it stops the plugin.
+				stop()
+			}()
+		default:
+			fatal("stdout-signal must be none or stdin")
+		}
+	} else if *influxEnabled {
+		client := newInfluxClient(*influxAddr, *influxToken)
+		writeAPI := client.WriteAPIBlocking(*influxOrg, *influxBucket)
+		sinks = append(sinks, &influxSink{writeAPI: writeAPI, tariff: tariff})
+		if *internalInflux {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				InternalMetricsWriter(ctx, writeAPI, *influxInterval)
+			}()
+		}
+		if *batteryCapacity > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				priceClients[0].BatteryPlanner(ctx, client.QueryAPI(*influxOrg), writeAPI)
+			}()
+		}
+
+		if *capacityCSV != "" || *capacityMeasurement != "" {
+			if *capacityPeaks < 1 {
+				fatal("capacity-peaks must be at least 1")
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				CapacityTracker(ctx, client.QueryAPI(*influxOrg), writeAPI)
 			}()
 		}
-	}()
+	}
+	if *remoteWriteURL != "" {
+		sinks = append(sinks, newRemoteWriteSink(*remoteWriteURL, tariff))
+	}
+	if *influxV1Addr != "" {
+		sink, err := newInfluxV1Sink(*influxV1Addr, tariff)
+		if err != nil {
+			fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if *influxV3Addr != "" {
+		sink, err := newInfluxV3Sink(*influxV3Addr, tariff)
+		if err != nil {
+			fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if *mqttBroker != "" {
+		if *mqttQoS < 0 || *mqttQoS > 2 {
+			fatal("mqtt-qos must be 0, 1 or 2")
+		}
+		sinks = append(sinks, newMQTTSink("mqtt", *mqttBroker, priceClients, nil, nil))
+	}
+	if *questDBAddr != "" {
+		sink, err := newQuestDBSink(*questDBAddr, tariff)
+		if err != nil {
+			fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if *postgresDSN != "" {
+		sink, err := newPostgresSink(context.Background(), *postgresDSN, *postgresTable, priceClients)
+		if err != nil {
+			fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if history != nil {
+		sinks = append(sinks, &historySink{store: history, pcs: priceClients})
+	}
+
+	var routes []*sinkRoute
+	for _, sink := range sinks {
+		routes = append(routes, newSinkRoute(sink))
+	}
+	if *sinksFile != "" {
+		configs, err := loadSinkConfigs(*sinksFile)
+		if err != nil {
+			fatal(err)
+		}
+		for _, c := range configs {
+			route, err := newConfiguredRoute(c, priceClients, tariff)
+			if err != nil {
+				fatal(err)
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	health := newHealthChecker(priceClients, routes)
+	if *httpAddr != "" {
+		// The admin endpoints are only served to admin tokens.
+		var admin *adminAPI
+		if len(auth.tokens) > 0 {
+			admin = newAdminAPI(priceClients, routes, history)
+		} else {
+			slog.Info("Admin API disabled, it needs -tokens")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Serving HTTP API", "addr", *httpAddr)
+			if err := listenAndServe(ctx, *httpAddr, withHealth(newHTTPHandler(priceClients, history, admin), health), auth, tlsConfig); err != nil {
+				fatal(err)
+			}
+		}()
+	}
+
+	if *metricsAddr != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Info("Serving Prometheus metrics", "addr", *metricsAddr)
+			if err := listenAndServe(ctx, *metricsAddr, withHealth(newMetricsHandler(priceClients), health), auth, tlsConfig); err != nil {
+				fatal(err)
+			}
+		}()
+	}
 
-	fmt.Println("Pushing prices to InfluxDB at update rate:", *influxInterval)
+	source := func(ctx context.Context) []Sample {
+		return currentSamples(ctx, priceClients, time.Now())
+	}
+	for _, route := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			route.run(ctx, source)
+		}()
+		route.logger().Info("Pushing prices", "interval", route.interval, "zones", route.zones)
+	}
 	wg.Wait()
+
+	slog.Info("Shutting down")
+	for _, route := range routes {
+		if err := closeSink(route.sink); err != nil {
+			route.logger().Error("Closing sink failed", "error", err)
+		}
+	}
+	if history != nil {
+		if err := history.Close(); err != nil {
+			slog.Error("Closing history store failed", "error", err)
+		}
+	}
+	// Flush the spans of the last writes.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Shutting down tracing failed", "error", err)
+	}
 }
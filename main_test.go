@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -199,9 +200,16 @@ func TestPriceLoader(t *testing.T) {
 	done := make(chan struct{})
 
 	// this should take around 2s to reload next days values, if it fails the timeout of 5s will fail the test
+	loaderCtx, stopLoader := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
 	go func() {
-		pc.PriceLoader()
+		defer close(stopped)
+		pc.PriceLoader(loaderCtx)
 	}()
+	t.Cleanup(func() {
+		stopLoader()
+		<-stopped
+	})
 
 	// run a verification loop on the loaded prices
 	go func() {
@@ -231,3 +239,79 @@ func TestPriceLoader(t *testing.T) {
 		break
 	}
 }
+
+func TestLoadTomorrowPrices(t *testing.T) {
+	loc, err := time.LoadLocation(Locale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 12, 0, 0, 0, loc),
+	}
+	clockSourceNow = fakec.Now
+	published := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/prices/2025/02-02_SE3.json":
+			fmt.Fprintln(w, day1)
+		case r.URL.Path == "/api/v1/prices/2025/02-03_SE3.json" && published:
+			fmt.Fprintln(w, day2)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	pc := PriceClient{
+		baseURL:    ts.URL,
+		client:     ts.Client(),
+		priceClass: "SE3",
+	}
+	if err := pc.LoadPrices(); err != nil {
+		t.Fatalf("LoadPrices() error got = %v, want = nil", err)
+	}
+	if err := pc.LoadTomorrowPrices(); !errors.Is(err, errNotPublished) {
+		t.Fatalf("LoadTomorrowPrices() error got = %v, want = %v", err, errNotPublished)
+	}
+	published = true
+	if err := pc.LoadTomorrowPrices(); err != nil {
+		t.Fatalf("LoadTomorrowPrices() error got = %v, want = nil", err)
+	}
+	if got := len(pc.Schedule(context.Background())); got != 48 {
+		t.Errorf("len(Schedule()) got = %d, want = 48", got)
+	}
+
+	// At midnight tomorrow's prices become today's.
+	fakec.curtime = time.Date(2025, 2, 3, 0, 0, 1, 0, loc)
+	if err := pc.LoadPrices(); err != nil {
+		t.Fatalf("LoadPrices() error got = %v, want = nil", err)
+	}
+	if got := len(pc.Schedule(context.Background())); got != 24 {
+		t.Errorf("len(Schedule()) got = %d, want = 24", got)
+	}
+}
+
+func TestParseZones(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single", in: "SE3", want: []string{"SE3"}},
+		{name: "several", in: "SE3, SE4", want: []string{"SE3", "SE4"}},
+		{name: "duplicates", in: "SE3,SE3,SE4,SE3", want: []string{"SE3", "SE4"}},
+		{name: "unknown", in: "SE3,SE5", wantErr: true},
+		{name: "empty entry", in: "SE3,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseZones(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseZones() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseZones() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var mirrorEnabled = flag.Bool("mirror", false, "Serve a caching mirror of the elprisetjustnu /api/v1/prices API on -httpaddr")
+
+// maxMirrorEntries bounds the cached days, the oldest fetched are evicted
+// beyond it.
+const maxMirrorEntries = 2048
+
+// mirrorCall is an upstream fetch that concurrent requests of the same day
+// wait for.
+type mirrorCall struct {
+	done   chan struct{}
+	prices Prices
+	err    error
+}
+
+// dayCache caches fetched days by key, evicting the oldest fetched beyond
+// max, maxPastDays if 0. The zero value is ready to use.
+type dayCache struct {
+	max int
+
+	mu       sync.Mutex
+	cache    map[string]Prices
+	order    []string
+	inflight map[string]*mirrorCall
+}
+
+// get returns the prices cached at key, calling fetch on a miss. Concurrent
+// misses of the same key share one fetch, failed fetches are not cached.
+func (c *dayCache) get(key string, fetch func() (Prices, error)) (Prices, error) {
+	c.mu.Lock()
+	if prices, ok := c.cache[key]; ok {
+		c.mu.Unlock()
+		return prices, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.prices, call.err
+	}
+	if c.inflight == nil {
+		c.cache = map[string]Prices{}
+		c.inflight = map[string]*mirrorCall{}
+	}
+	call := &mirrorCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.prices, call.err = fetch()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		c.cache[key] = call.prices
+		c.order = append(c.order, key)
+		if len(c.order) > cmp.Or(c.max, maxPastDays) {
+			delete(c.cache, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.prices, call.err
+}
+
+// priceMirror serves /api/v1/prices/{year}/{MM-DD}_{zone}.json like the
+// upstream API. Loaded prices are served from the price clients, other days
+// are fetched upstream once and cached.
+type priceMirror struct {
+	pcs     PriceClients
+	baseURL string
+	client  *http.Client
+	days    dayCache
+}
+
+func newPriceMirror(pcs PriceClients, baseURL string) *priceMirror {
+	return &priceMirror{
+		pcs:     pcs,
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		days:    dayCache{max: maxMirrorEntries},
+	}
+}
+
+// get returns the prices of the zone's day, fetching them upstream on a
+// cache miss.
+func (m *priceMirror) get(zone string, date time.Time) (Prices, error) {
+	if pc := m.pcs.Zone(zone); pc != nil && pc.Zone() == zone {
+		if prices, ok := pc.LoadedFor(date); ok {
+			return prices, nil
+		}
+	}
+	return m.days.get(zone+"/"+date.Format(time.DateOnly), func() (Prices, error) {
+		upstream := &PriceClient{baseURL: m.baseURL, priceClass: zone, client: m.client}
+		ctx, span := tracer.Start(context.Background(), "mirror.fetch",
+			trace.WithAttributes(zoneKey.String(zone), dateAttr(date), providerKey.String(Provider)))
+		prices, err := upstream.fetchPrices(ctx, upstream.apiURLFor(date))
+		endSpan(span, err)
+		return prices, err
+	})
+}
+
+// parseMirrorPath parses the year and file path values of
+// /api/v1/prices/{year}/{MM-DD}_{zone}.json.
+func parseMirrorPath(year, file string) (string, time.Time, error) {
+	name, ok := strings.CutSuffix(file, ".json")
+	if !ok {
+		return "", time.Time{}, fmt.Errorf("invalid file %q", file)
+	}
+	day, zone, ok := strings.Cut(name, "_")
+	if !ok || !slices.Contains(priceClasses, zone) {
+		return "", time.Time{}, fmt.Errorf("invalid zone in %q", file)
+	}
+	date, err := time.ParseInLocation("2006-01-02", year+"-"+day, locale)
+	if err != nil || len(year) != 4 || len(day) != 5 {
+		return "", time.Time{}, fmt.Errorf("invalid date %s/%s", year, day)
+	}
+	return zone, date, nil
+}
+
+func (m *priceMirror) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	zone, date, err := parseMirrorPath(r.PathValue("year"), r.PathValue("file"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	prices, err := m.get(zone, date)
+	if errors.Is(err, errNotPublished) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Warn("Mirror fetch failed", "zone", zone, "provider", Provider, "date", date.Format(time.DateOnly), "error", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	year, month, day := clockSourceNow().In(locale).Date()
+	if date.Before(time.Date(year, month, day, 0, 0, 0, 0, locale)) {
+		// Past days do not change.
+		w.Header().Set("Cache-Control", cacheScope()+", max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", cacheScope()+", max-age=300")
+	}
+	writeJSON(w, prices)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPriceMirror(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		if r.URL.Path != "/api/v1/prices/2025/02-01_SE4.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, day1)
+	}))
+	defer upstream.Close()
+
+	mirror := newPriceMirror(PriceClients{loadedPriceClient(t)}, upstream.URL)
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/prices/{year}/{file}", mirror)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path string) (int, Prices) {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Error(err)
+			return 0, nil
+		}
+		defer resp.Body.Close()
+		var prices Prices
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&prices); err != nil {
+				t.Error(err)
+			}
+		}
+		return resp.StatusCode, prices
+	}
+
+	// Concurrent misses of the same day are fetched upstream once.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, prices := get("/api/v1/prices/2025/02-01_SE4.json"); status != http.StatusOK || len(prices) != 24 {
+				t.Errorf("concurrent get got = %d with %d prices, want = 200 with 24", status, len(prices))
+			}
+		}()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("upstream fetches got = %d, want = 1", got)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantFetches int32
+	}{
+		{name: "cached", path: "/api/v1/prices/2025/02-01_SE4.json", wantStatus: http.StatusOK, wantFetches: 1},
+		{name: "loaded today", path: "/api/v1/prices/2025/02-02_SE3.json", wantStatus: http.StatusOK, wantFetches: 1},
+		{name: "loaded tomorrow", path: "/api/v1/prices/2025/02-03_SE3.json", wantStatus: http.StatusOK, wantFetches: 1},
+		{name: "not published", path: "/api/v1/prices/2025/02-04_SE3.json", wantStatus: http.StatusNotFound, wantFetches: 2},
+		{name: "unknown zone", path: "/api/v1/prices/2025/02-01_SE9.json", wantStatus: http.StatusNotFound, wantFetches: 2},
+		{name: "invalid date", path: "/api/v1/prices/2025/02-30_SE3.json", wantStatus: http.StatusNotFound, wantFetches: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := get(tt.path); status != tt.wantStatus {
+				t.Errorf("status got = %d, want = %d", status, tt.wantStatus)
+			}
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("upstream fetches got = %d, want = %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
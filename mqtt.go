@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+var mqttBroker = flag.String("mqtt-broker", "", "MQTT broker, e.g. tcp://localhost:1883, disabled if empty")
+var mqttUsername = flag.String("mqtt-username", "", "MQTT username")
+var mqttPassword = flag.String("mqtt-password", "", "MQTT password")
+var mqttClientID = flag.String("mqtt-clientid", "price2influx", "MQTT client id, routes of the -sinks file append their name")
+var mqttTopic = flag.String("mqtt-topic", "price2influx/{zone}/{sensor}", "MQTT topic template, {zone} and {sensor} are replaced")
+var mqttAvailabilityTopic = flag.String("mqtt-availability-topic", "price2influx/status", "MQTT topic for the online/offline availability, routes of the -sinks file append /name")
+var mqttQoS = flag.Int("mqtt-qos", 1, "MQTT QoS, 0, 1 or 2")
+var mqttRetain = flag.Bool("mqtt-retain", true, "Retain the published MQTT messages")
+var mqttDiscovery = flag.Bool("mqtt-discovery", true, "Publish Home Assistant MQTT discovery configs")
+var mqttDiscoveryPrefix = flag.String("mqtt-discovery-prefix", "homeassistant", "Home Assistant MQTT discovery prefix")
+
+// mqttSink publishes the current price, the next price, the price level and
+// the schedule of every zone. Unchanged payloads are not published again.
+// Discovery configs are announced for the zones and fields of its route.
+type mqttSink struct {
+	name         string
+	client       mqtt.Client
+	pcs          PriceClients
+	zones        []string
+	fields       []string
+	topic        string
+	availability string
+	qos          byte
+	retain       bool
+	discovery    string
+
+	mu        sync.Mutex
+	published map[string]string
+}
+
+// newMQTTSink connects to the broker in the background, reconnecting with
+// backoff. The availability topic is set to offline by the broker through
+// the last will if the connection is lost. Sinks not named mqtt, the routes
+// of the -sinks file, append their name to the client id and availability
+// topic: a broker drops the older of two connections with the same id, and
+// one route going offline must not mark the entities of the others. zones
+// and fields are the selection of the route, all if empty.
+func newMQTTSink(name, broker string, pcs PriceClients, zones, fields []string) *mqttSink {
+	clientID, availability := *mqttClientID, *mqttAvailabilityTopic
+	if name != "mqtt" {
+		clientID += "-" + name
+		availability += "/" + name
+	}
+	s := &mqttSink{
+		name:         name,
+		pcs:          pcs,
+		zones:        zones,
+		fields:       fields,
+		topic:        *mqttTopic,
+		availability: availability,
+		qos:          byte(*mqttQoS),
+		retain:       *mqttRetain,
+	}
+	if *mqttDiscovery {
+		s.discovery = *mqttDiscoveryPrefix
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(*mqttUsername).
+		SetPassword(*mqttPassword).
+		SetWill(s.availability, "offline", s.qos, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(s.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			s.logger().Warn("MQTT connection lost", "error", err)
+		})
+	s.client = mqtt.NewClient(opts)
+	s.client.Connect()
+	return s
+}
+
+func (s *mqttSink) Name() string {
+	return s.name
+}
+
+// onConnect announces the availability and discovery configs, and forgets
+// what was published since a new session may have lost it.
+func (s *mqttSink) onConnect(c mqtt.Client) {
+	s.mu.Lock()
+	s.published = nil
+	s.mu.Unlock()
+	c.Publish(s.availability, s.qos, true, "online")
+	if s.discovery == "" {
+		return
+	}
+	for topic, config := range s.discoveryConfigs() {
+		payload, err := json.Marshal(config)
+		if err != nil {
+			s.logger().Error("Encoding MQTT discovery config failed", "topic", topic, "error", err)
+			continue
+		}
+		c.Publish(topic, s.qos, true, payload)
+	}
+}
+
+// selected reports whether the route of the sink sends the field of the
+// zone.
+func (s *mqttSink) selected(zone, field string) bool {
+	return (len(s.zones) == 0 || slices.Contains(s.zones, zone)) && (len(s.fields) == 0 || slices.Contains(s.fields, field))
+}
+
+func (s *mqttSink) sensorTopic(zone, sensor string) string {
+	return strings.NewReplacer("{zone}", zone, "{sensor}", sensor).Replace(s.topic)
+}
+
+// discoveryConfigs returns the Home Assistant configs of the selected
+// sensors by topic.
+func (s *mqttSink) discoveryConfigs() map[string]map[string]any {
+	configs := map[string]map[string]any{}
+	for _, pc := range s.pcs {
+		s.addDiscoveryConfigs(configs, pc.Zone())
+	}
+	return configs
+}
+
+// addDiscoveryConfigs adds the configs of the selected sensors of the zone.
+func (s *mqttSink) addDiscoveryConfigs(configs map[string]map[string]any, zone string) {
+	device := map[string]any{
+		"identifiers":  []string{"price2influx_" + strings.ToLower(zone)},
+		"name":         "Electricity price " + zone,
+		"manufacturer": "price2influx",
+	}
+	for _, sensor := range []struct {
+		id, name, unit string
+		attributes     bool
+	}{
+		{"price", "Price", "SEK/kWh", true},
+		{"next_price", "Next price", "SEK/kWh", false},
+		{"level", "Price level", "", false},
+	} {
+		if !s.selected(zone, sensor.id) {
+			continue
+		}
+		id := fmt.Sprintf("price2influx_%s_%s", strings.ToLower(zone), sensor.id)
+		config := map[string]any{
+			"name":               sensor.name,
+			"unique_id":          id,
+			"object_id":          id,
+			"state_topic":        s.sensorTopic(zone, sensor.id),
+			"availability_topic": s.availability,
+			"device":             device,
+		}
+		if sensor.unit != "" {
+			config["unit_of_measurement"] = sensor.unit
+			config["state_class"] = "measurement"
+		}
+		if sensor.attributes && s.selected(zone, "schedule") {
+			config["json_attributes_topic"] = s.sensorTopic(zone, "schedule")
+		}
+		configs[fmt.Sprintf("%s/sensor/%s/config", s.discovery, id)] = config
+	}
+}
+
+func (s *mqttSink) Write(ctx context.Context, samples []Sample) error {
+	if !s.client.IsConnectionOpen() {
+		return fmt.Errorf("not connected to MQTT broker")
+	}
+	for _, sample := range samples {
+		pc := s.pcs.Zone(sample.Zone)
+		if pc == nil {
+			continue
+		}
+		schedule, err := json.Marshal(map[string]Prices{"prices": pc.Schedule(ctx)})
+		if err != nil {
+			return err
+		}
+		payloads := map[string]string{
+			"price":    strconv.FormatFloat(sample.Price.SEKPerkWh, 'f', -1, 64),
+			"level":    pc.Today().Level(sample.Price),
+			"schedule": string(schedule),
+		}
+		if next, err := pc.NextPrice(); err == nil {
+			payloads["next_price"] = strconv.FormatFloat(next.SEKPerkWh, 'f', -1, 64)
+		}
+		for sensor, payload := range payloads {
+			if !sample.Selected(sensor) {
+				continue
+			}
+			if err := s.publish(ctx, s.sensorTopic(sample.Zone, sensor), payload); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *mqttSink) publish(ctx context.Context, topic, payload string) error {
+	s.mu.Lock()
+	unchanged := s.published[topic] == payload
+	s.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+	token := s.client.Publish(topic, s.qos, s.retain, payload)
+	select {
+	case <-token.Done():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("publish to %s: %v", topic, err)
+	}
+	s.mu.Lock()
+	if s.published == nil {
+		s.published = map[string]string{}
+	}
+	s.published[topic] = payload
+	s.mu.Unlock()
+	return nil
+}
+
+// Close marks the sink offline and disconnects.
+func (s *mqttSink) Close() {
+	s.client.Publish(s.availability, s.qos, true, "offline").WaitTimeout(time.Second)
+	s.client.Disconnect(250)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/go-cmp/cmp"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// startBroker runs an embedded MQTT broker and returns its address.
+func startBroker(t *testing.T) (*mochi.Server, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	server := mochi.New(&mochi.Options{InlineClient: true})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "test", Address: addr})); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, addr
+}
+
+func TestMQTTSink(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 19, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	server, addr := startBroker(t)
+
+	var mu sync.Mutex
+	messages := map[string]string{}
+	err := server.Subscribe("#", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		mu.Lock()
+		defer mu.Unlock()
+		messages[pk.TopicName] = string(pk.Payload)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := loadedPriceClient(t)
+	s := &mqttSink{
+		pcs:          PriceClients{pc},
+		topic:        "price2influx/{zone}/{sensor}",
+		availability: "price2influx/status",
+		qos:          1,
+		retain:       true,
+		discovery:    "homeassistant",
+	}
+	s.client = mqtt.NewClient(mqtt.NewClientOptions().
+		AddBroker("tcp://"+addr).
+		SetClientID("test").
+		SetWill(s.availability, "offline", 1, true).
+		SetOnConnectHandler(s.onConnect))
+	if token := s.client.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatal(token.Error())
+	}
+	defer s.Close()
+
+	current, err := pc.CurrentPrice(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), []Sample{{Zone: "SE3", Price: current}}); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+
+	want := map[string]string{
+		"price2influx/status":         "online",
+		"price2influx/SE3/price":      "1.14251",
+		"price2influx/SE3/next_price": "0.99809",
+		"price2influx/SE3/level":      "very_expensive",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		missing := ""
+		for topic, payload := range want {
+			if messages[topic] != payload {
+				missing = topic
+			}
+		}
+		got := messages
+		mu.Unlock()
+		if missing == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("topic %s got = %q, want = %q", missing, got[missing], want[missing])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var schedule map[string]Prices
+	if err := json.Unmarshal([]byte(messages["price2influx/SE3/schedule"]), &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if len(schedule["prices"]) != 48 {
+		t.Errorf("schedule got %d prices, want = 48", len(schedule["prices"]))
+	}
+	var config map[string]any
+	if err := json.Unmarshal([]byte(messages["homeassistant/sensor/price2influx_se3_price/config"]), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config["state_topic"] != "price2influx/SE3/price" || !strings.HasSuffix(config["json_attributes_topic"].(string), "/schedule") {
+		t.Errorf("discovery config got = %v", config)
+	}
+}
+
+func TestMQTTDiscoverySelection(t *testing.T) {
+	pcs := PriceClients{{priceClass: "SE3"}, {priceClass: "SE4"}}
+	tests := []struct {
+		name   string
+		zones  []string
+		fields []string
+		want   map[string]bool
+	}{
+		{
+			name: "all",
+			want: map[string]bool{
+				"price2influx_se3_price": true, "price2influx_se3_next_price": false, "price2influx_se3_level": false,
+				"price2influx_se4_price": true, "price2influx_se4_next_price": false, "price2influx_se4_level": false,
+			},
+		},
+		{
+			name:   "SE3 price",
+			zones:  []string{"SE3"},
+			fields: []string{"price"},
+			want:   map[string]bool{"price2influx_se3_price": false},
+		},
+		{
+			name:   "SE4 price and schedule",
+			zones:  []string{"SE4"},
+			fields: []string{"price", "schedule"},
+			want:   map[string]bool{"price2influx_se4_price": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &mqttSink{pcs: pcs, zones: tt.zones, fields: tt.fields, topic: "price2influx/{zone}/{sensor}", discovery: "homeassistant"}
+			got := map[string]bool{}
+			for _, config := range s.discoveryConfigs() {
+				_, attributes := config["json_attributes_topic"]
+				got[config["unique_id"].(string)] = attributes
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("discovery configs mismatch, unique_id: has attributes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanRequest describes an appliance run that should be scheduled.
+type PlanRequest struct {
+	Duration time.Duration
+	Earliest time.Time
+	Deadline time.Time
+	// Profile is the power draw in kW for equally long consecutive steps of
+	// the run. An empty profile means a constant draw of 1 kW.
+	Profile []float64
+}
+
+// PlanInterval is a period of time in which the appliance should run.
+type PlanInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Cost  float64   `json:"cost"`
+}
+
+// Plan holds the cheapest contiguous run as well as the cheapest set of
+// non-contiguous intervals for a PlanRequest. Costs are in SEK.
+type Plan struct {
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Cost          float64        `json:"cost"`
+	Intervals     []PlanInterval `json:"intervals"`
+	IntervalsCost float64        `json:"intervals_cost"`
+}
+
+var errNoPrices = errors.New("no prices available for the requested window")
+
+// maxProfileSteps bounds the power profile of a PlanRequest, the contiguous
+// search evaluates every step at every price and profile boundary. It is a
+// day in quarter hours.
+const maxProfileSteps = 96
+
+// FindCheapestWindow plans the request using the prices loaded for today and tomorrow.
+func (p *PriceClient) FindCheapestWindow(req PlanRequest) (*Plan, error) {
+	return planCheapest(p.Schedule(context.Background()), req)
+}
+
+func planCheapest(prices Prices, req PlanRequest) (*Plan, error) {
+	if req.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	if req.Deadline.Sub(req.Earliest) < req.Duration {
+		return nil, fmt.Errorf("window %s - %s is shorter than the duration %s", req.Earliest, req.Deadline, req.Duration)
+	}
+	if len(req.Profile) > maxProfileSteps {
+		return nil, fmt.Errorf("power profile longer than %d steps", maxProfileSteps)
+	}
+	for _, kw := range req.Profile {
+		if kw < 0 || math.IsNaN(kw) || math.IsInf(kw, 0) {
+			return nil, fmt.Errorf("power profile values must be finite and not negative")
+		}
+	}
+	profile := req.Profile
+	if len(profile) == 0 {
+		profile = []float64{1}
+	}
+	if req.Duration/time.Duration(len(profile)) <= 0 {
+		return nil, fmt.Errorf("duration %s is too short for %d profile steps", req.Duration, len(profile))
+	}
+
+	prices = append(Prices(nil), prices...)
+	sort.Slice(prices, func(i, j int) bool { return prices[i].TimeStart.Before(prices[j].TimeStart) })
+
+	plan := &Plan{}
+	if !planContiguous(prices, req.Earliest, req.Deadline, req.Duration, profile, plan) {
+		return nil, errNoPrices
+	}
+	planIntervals(prices, req.Earliest, req.Deadline, req.Duration, mean(profile), plan)
+	return plan, nil
+}
+
+// planContiguous finds the cheapest start time for an uninterrupted run. With
+// piecewise constant prices and power the optimum starts or ends on a price
+// or profile boundary, so only those start times are evaluated. The last
+// profile step runs until the end of the duration.
+func planContiguous(prices Prices, earliest, deadline time.Time, d time.Duration, profile []float64, plan *Plan) bool {
+	latest := deadline.Add(-d)
+	step := d / time.Duration(len(profile))
+	candidates := []time.Time{earliest, latest}
+	for _, price := range prices {
+		for _, boundary := range []time.Time{price.TimeStart, price.TimeEnd} {
+			for k := 0; k <= len(profile); k++ {
+				candidates = append(candidates, boundary.Add(-time.Duration(k)*step))
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	integral := newPriceIntegral(prices)
+	found := false
+	for i, start := range candidates {
+		if start.Before(earliest) || start.After(latest) || (i > 0 && start.Equal(candidates[i-1])) {
+			continue
+		}
+		cost := 0.0
+		covered := true
+		for k, kw := range profile {
+			from, to := start.Add(time.Duration(k)*step), start.Add(time.Duration(k+1)*step)
+			if k == len(profile)-1 {
+				to = start.Add(d)
+			}
+			c, ok := integral.energyCost(from, to, kw)
+			if !ok {
+				covered = false
+				break
+			}
+			cost += c
+		}
+		if covered && (!found || cost < plan.Cost) {
+			found = true
+			plan.Start, plan.End, plan.Cost = start, start.Add(d), cost
+		}
+	}
+	return found
+}
+
+// planIntervals picks the cheapest price intervals inside the window until
+// the requested duration is covered, using the mean power of the profile.
+func planIntervals(prices Prices, earliest, deadline time.Time, d time.Duration, kw float64, plan *Plan) {
+	var inside Prices
+	for _, price := range prices {
+		if price.TimeStart.Before(earliest) {
+			price.TimeStart = earliest
+		}
+		if price.TimeEnd.After(deadline) {
+			price.TimeEnd = deadline
+		}
+		if price.TimeEnd.After(price.TimeStart) {
+			inside = append(inside, price)
+		}
+	}
+	sort.SliceStable(inside, func(i, j int) bool { return inside[i].SEKPerkWh < inside[j].SEKPerkWh })
+
+	var chosen []PlanInterval
+	remaining := d
+	for _, price := range inside {
+		if remaining <= 0 {
+			break
+		}
+		length := price.TimeEnd.Sub(price.TimeStart)
+		if length > remaining {
+			length = remaining
+		}
+		remaining -= length
+		chosen = append(chosen, PlanInterval{
+			Start: price.TimeStart,
+			End:   price.TimeStart.Add(length),
+			Cost:  price.SEKPerkWh * length.Hours() * kw,
+		})
+	}
+	sort.Slice(chosen, func(i, j int) bool { return chosen[i].Start.Before(chosen[j].Start) })
+
+	plan.Intervals = nil
+	plan.IntervalsCost = 0
+	for _, interval := range chosen {
+		plan.IntervalsCost += interval.Cost
+		if n := len(plan.Intervals); n > 0 && plan.Intervals[n-1].End.Equal(interval.Start) {
+			plan.Intervals[n-1].End = interval.End
+			plan.Intervals[n-1].Cost += interval.Cost
+			continue
+		}
+		plan.Intervals = append(plan.Intervals, interval)
+	}
+}
+
+// priceIntegral answers energy cost queries over sorted, non-overlapping
+// prices in logarithmic time using prefix sums.
+type priceIntegral struct {
+	prices Prices
+	// cost and covered are the SEK per kW and the time covered by the
+	// prices before index i.
+	cost    []float64
+	covered []time.Duration
+}
+
+func newPriceIntegral(prices Prices) *priceIntegral {
+	p := &priceIntegral{prices: prices, cost: make([]float64, len(prices)+1), covered: make([]time.Duration, len(prices)+1)}
+	for i, price := range prices {
+		length := price.TimeEnd.Sub(price.TimeStart)
+		p.cost[i+1] = p.cost[i] + price.SEKPerkWh*length.Hours()
+		p.covered[i+1] = p.covered[i] + length
+	}
+	return p
+}
+
+// at returns the SEK per kW and the time covered by the prices before t.
+func (p *priceIntegral) at(t time.Time) (float64, time.Duration) {
+	i := sort.Search(len(p.prices), func(i int) bool { return p.prices[i].TimeEnd.After(t) })
+	cost, covered := p.cost[i], p.covered[i]
+	if i < len(p.prices) && t.After(p.prices[i].TimeStart) {
+		partial := t.Sub(p.prices[i].TimeStart)
+		cost += p.prices[i].SEKPerkWh * partial.Hours()
+		covered += partial
+	}
+	return cost, covered
+}
+
+// energyCost returns the cost of drawing kw between from and to, and false
+// if the prices do not cover the whole period.
+func (p *priceIntegral) energyCost(from, to time.Time, kw float64) (float64, bool) {
+	fromCost, fromCovered := p.at(from)
+	toCost, toCovered := p.at(to)
+	return (toCost - fromCost) * kw, toCovered-fromCovered == to.Sub(from)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// parseTime accepts RFC3339 or a local time without zone, e.g. 2025-02-02T22:00.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02T15:04", s, locale)
+}
+
+// parseProfile parses a comma separated list of kW values.
+func parseProfile(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var profile []float64
+	for _, field := range strings.Split(s, ",") {
+		kw, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid power profile %q: %v", s, err)
+		}
+		profile = append(profile, kw)
+	}
+	return profile, nil
+}
+
+// parsePlanRequest builds a PlanRequest from its string form. An empty
+// earliest means now and an empty deadline means the end of the schedule.
+func parsePlanRequest(duration, earliest, deadline, profile string, schedule Prices) (PlanRequest, error) {
+	var req PlanRequest
+	var err error
+	if req.Duration, err = time.ParseDuration(duration); err != nil {
+		return req, fmt.Errorf("invalid duration %q: %v", duration, err)
+	}
+	req.Earliest = clockSourceNow()
+	if earliest != "" {
+		if req.Earliest, err = parseTime(earliest); err != nil {
+			return req, fmt.Errorf("invalid earliest start %q: %v", earliest, err)
+		}
+	}
+	if deadline != "" {
+		if req.Deadline, err = parseTime(deadline); err != nil {
+			return req, fmt.Errorf("invalid deadline %q: %v", deadline, err)
+		}
+	} else {
+		for _, price := range schedule {
+			if price.TimeEnd.After(req.Deadline) {
+				req.Deadline = price.TimeEnd
+			}
+		}
+	}
+	req.Profile, err = parseProfile(profile)
+	return req, err
+}
+
+// runPlan implements the plan subcommand, printing the plan as JSON.
+func runPlan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	class := fs.String("priceclass", "SE3", fmt.Sprintf("Priceclass, one of: %v", priceClasses))
+	duration := fs.String("duration", "1h", "Run duration")
+	earliest := fs.String("earliest", "", "Earliest start, RFC3339 or 2006-01-02T15:04 local time, defaults to now")
+	deadline := fs.String("deadline", "", "Deadline for the run to finish, defaults to the end of the loaded prices")
+	profile := fs.String("profile", "", "Comma separated power draw in kW for equally long steps of the run")
+	fs.Parse(args)
+
+	pc := NewPriceClient(*class)
+	if err := pc.LoadPrices(); err != nil {
+		return err
+	}
+	if err := pc.LoadTomorrowPrices(); err != nil && !errors.Is(err, errNotPublished) {
+		return err
+	}
+	req, err := parsePlanRequest(*duration, *earliest, *deadline, *profile, pc.Schedule(context.Background()))
+	if err != nil {
+		return err
+	}
+	plan, err := pc.FindCheapestWindow(req)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func loadedPriceClient(t *testing.T) *PriceClient {
+	t.Helper()
+	pc := &PriceClient{priceClass: "SE3"}
+	if err := json.Unmarshal([]byte(day1), &pc.prices); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(day2), &pc.tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func TestFindCheapestWindow(t *testing.T) {
+	loc, err := time.LoadLocation(Locale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evening := time.Date(2025, 2, 2, 20, 0, 0, 0, loc)
+	morning := time.Date(2025, 2, 3, 6, 0, 0, 0, loc)
+	tests := []struct {
+		name     string
+		req      PlanRequest
+		wantErr  bool
+		wantPlan *Plan
+	}{
+		{
+			name: "contiguous within today",
+			req:  PlanRequest{Duration: 3 * time.Hour, Earliest: time.Date(2025, 2, 2, 0, 0, 0, 0, loc), Deadline: time.Date(2025, 2, 3, 0, 0, 0, 0, loc)},
+			wantPlan: &Plan{
+				Start: time.Date(2025, 2, 2, 2, 0, 0, 0, loc), End: time.Date(2025, 2, 2, 5, 0, 0, 0, loc), Cost: 1.0281,
+				Intervals:     []PlanInterval{{Start: time.Date(2025, 2, 2, 2, 0, 0, 0, loc), End: time.Date(2025, 2, 2, 5, 0, 0, 0, loc), Cost: 1.0281}},
+				IntervalsCost: 1.0281,
+			},
+		},
+		{
+			name: "across midnight",
+			req:  PlanRequest{Duration: 2 * time.Hour, Earliest: evening, Deadline: morning},
+			wantPlan: &Plan{
+				Start: time.Date(2025, 2, 3, 2, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 0, 0, 0, loc), Cost: 0.81227,
+				Intervals: []PlanInterval{
+					{Start: time.Date(2025, 2, 3, 1, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 2, 0, 0, 0, loc), Cost: 0.40774},
+					{Start: time.Date(2025, 2, 3, 3, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 0, 0, 0, loc), Cost: 0.40418},
+				},
+				IntervalsCost: 0.81192,
+			},
+		},
+		{
+			name: "partial interval",
+			req:  PlanRequest{Duration: 90 * time.Minute, Earliest: evening, Deadline: morning},
+			wantPlan: &Plan{
+				Start: time.Date(2025, 2, 3, 2, 30, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 0, 0, 0, loc), Cost: 0.40809/2 + 0.40418,
+				Intervals: []PlanInterval{
+					{Start: time.Date(2025, 2, 3, 1, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 1, 30, 0, 0, loc), Cost: 0.40774 / 2},
+					{Start: time.Date(2025, 2, 3, 3, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 0, 0, 0, loc), Cost: 0.40418},
+				},
+				IntervalsCost: 0.40774/2 + 0.40418,
+			},
+		},
+		{
+			name: "power profile",
+			req:  PlanRequest{Duration: 90 * time.Minute, Earliest: evening, Deadline: morning, Profile: []float64{2, 0, 0}},
+			wantPlan: &Plan{
+				Start: time.Date(2025, 2, 3, 3, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 30, 0, 0, loc), Cost: 0.40418,
+				Intervals: []PlanInterval{
+					{Start: time.Date(2025, 2, 3, 1, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 1, 30, 0, 0, loc), Cost: 0.40774 / 2 * 2 / 3},
+					{Start: time.Date(2025, 2, 3, 3, 0, 0, 0, loc), End: time.Date(2025, 2, 3, 4, 0, 0, 0, loc), Cost: 0.40418 * 2 / 3},
+				},
+				IntervalsCost: (0.40774/2 + 0.40418) * 2 / 3,
+			},
+		},
+		{
+			name:    "window shorter than duration",
+			req:     PlanRequest{Duration: 3 * time.Hour, Earliest: evening, Deadline: evening.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "profile too long",
+			req:     PlanRequest{Duration: 3 * time.Hour, Earliest: evening, Deadline: morning, Profile: make([]float64, maxProfileSteps+1)},
+			wantErr: true,
+		},
+		{
+			name:    "profile steps shorter than a nanosecond",
+			req:     PlanRequest{Duration: 2, Earliest: evening, Deadline: morning, Profile: []float64{1, 1, 1}},
+			wantErr: true,
+		},
+		{
+			name:    "NaN in profile",
+			req:     PlanRequest{Duration: time.Hour, Earliest: evening, Deadline: morning, Profile: []float64{1, math.NaN()}},
+			wantErr: true,
+		},
+		{
+			name:    "no prices for window",
+			req:     PlanRequest{Duration: time.Hour, Earliest: morning.AddDate(0, 0, 2), Deadline: morning.AddDate(0, 0, 3)},
+			wantErr: true,
+		},
+	}
+
+	pc := loadedPriceClient(t)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			plan, err := pc.FindCheapestWindow(tc.req)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("FindCheapestWindow() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if diff := cmp.Diff(tc.wantPlan, plan, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("FindCheapestWindow() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlanCheapestProratedLastStep(t *testing.T) {
+	start := time.Date(2025, 2, 2, 0, 0, 0, 0, locale)
+	// 10ns in 3 steps of 3ns, the last step runs 4ns.
+	plan, err := planCheapest(hourlyPrices(start, 2), PlanRequest{Duration: 10, Earliest: start, Deadline: start.Add(time.Hour), Profile: []float64{1, 1, 3}})
+	if err != nil {
+		t.Fatalf("planCheapest() error = %v", err)
+	}
+	want := 2 * (6 + 4*3) * time.Nanosecond.Hours()
+	if !plan.End.Equal(start.Add(10)) || math.Abs(plan.Cost-want) > want*1e-9 {
+		t.Errorf("plan got end = %v, cost = %v, want end = %v, cost = %v", plan.End, plan.Cost, start.Add(10), want)
+	}
+}
+
+func TestPlanCheapestLongProfile(t *testing.T) {
+	start := time.Date(2025, 2, 2, 0, 0, 0, 0, locale)
+	var prices Prices
+	profile := make([]float64, maxProfileSteps)
+	for i := range 192 {
+		prices = append(prices, Price{
+			SEKPerkWh: float64((i*37)%19) / 10,
+			TimeStart: start.Add(time.Duration(i) * 15 * time.Minute),
+			TimeEnd:   start.Add(time.Duration(i+1) * 15 * time.Minute),
+		})
+	}
+	for i := range profile {
+		profile[i] = float64(i%4 + 1)
+	}
+	begin := time.Now()
+	plan, err := planCheapest(prices, PlanRequest{Duration: 24 * time.Hour, Earliest: start, Deadline: start.Add(48 * time.Hour), Profile: profile})
+	if err != nil {
+		t.Fatalf("planCheapest() error = %v", err)
+	}
+	if took := time.Since(begin); took > 2*time.Second {
+		t.Errorf("planCheapest() took %s", took)
+	}
+	cost := 0.0
+	for k, kw := range profile {
+		from := plan.Start.Add(time.Duration(k) * 15 * time.Minute)
+		for _, p := range prices {
+			if !p.TimeStart.Before(from) && p.TimeStart.Before(from.Add(15*time.Minute)) {
+				cost += p.SEKPerkWh * kw / 4
+			}
+		}
+	}
+	if math.Abs(plan.Cost-cost) > 1e-9 {
+		t.Errorf("plan cost got = %v, want = %v", plan.Cost, cost)
+	}
+}
+
+func TestPlanHandler(t *testing.T) {
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{loadedPriceClient(t)}, nil, nil))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/plan?duration=2h&earliest=2025-02-02T20:00&deadline=2025-02-03T06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status got = %d, want = %d", resp.StatusCode, http.StatusOK)
+	}
+	var plan Plan
+	if err := json.NewDecoder(resp.Body).Decode(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2025-02-03T02:00:00+01:00"; plan.Start.Format(time.RFC3339) != want {
+		t.Errorf("start got = %s, want = %s", plan.Start.Format(time.RFC3339), want)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/plan?duration=bogus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status got = %d, want = %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
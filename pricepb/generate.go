@@ -0,0 +1,5 @@
+// Package pricepb holds the protobuf messages and gRPC service of the
+// price2influx gRPC API, generated from price.proto.
+package pricepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative price.proto
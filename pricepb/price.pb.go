@@ -0,0 +1,1008 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: price.proto
+
+package pricepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Price is a price interval, prices are per kWh.
+type Price struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SekPerKwh     float64                `protobuf:"fixed64,1,opt,name=sek_per_kwh,json=sekPerKwh,proto3" json:"sek_per_kwh,omitempty"`
+	EurPerKwh     float64                `protobuf:"fixed64,2,opt,name=eur_per_kwh,json=eurPerKwh,proto3" json:"eur_per_kwh,omitempty"`
+	Exr           float64                `protobuf:"fixed64,3,opt,name=exr,proto3" json:"exr,omitempty"`
+	TimeStart     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time_start,json=timeStart,proto3" json:"time_start,omitempty"`
+	TimeEnd       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time_end,json=timeEnd,proto3" json:"time_end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Price) Reset() {
+	*x = Price{}
+	mi := &file_price_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Price) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Price) ProtoMessage() {}
+
+func (x *Price) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Price.ProtoReflect.Descriptor instead.
+func (*Price) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Price) GetSekPerKwh() float64 {
+	if x != nil {
+		return x.SekPerKwh
+	}
+	return 0
+}
+
+func (x *Price) GetEurPerKwh() float64 {
+	if x != nil {
+		return x.EurPerKwh
+	}
+	return 0
+}
+
+func (x *Price) GetExr() float64 {
+	if x != nil {
+		return x.Exr
+	}
+	return 0
+}
+
+func (x *Price) GetTimeStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeStart
+	}
+	return nil
+}
+
+func (x *Price) GetTimeEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.TimeEnd
+	}
+	return nil
+}
+
+type GetCurrentPriceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Zone is the priceclass, e.g. SE3, the first configured if empty.
+	Zone          string `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentPriceRequest) Reset() {
+	*x = GetCurrentPriceRequest{}
+	mi := &file_price_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentPriceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentPriceRequest) ProtoMessage() {}
+
+func (x *GetCurrentPriceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentPriceRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentPriceRequest) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCurrentPriceRequest) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+type GetCurrentPriceResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Zone  string                 `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	Price *Price                 `protobuf:"bytes,2,opt,name=price,proto3" json:"price,omitempty"`
+	// Level is very_cheap, cheap, normal, expensive or very_expensive.
+	Level         string `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentPriceResponse) Reset() {
+	*x = GetCurrentPriceResponse{}
+	mi := &file_price_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentPriceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentPriceResponse) ProtoMessage() {}
+
+func (x *GetCurrentPriceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentPriceResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentPriceResponse) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCurrentPriceResponse) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *GetCurrentPriceResponse) GetPrice() *Price {
+	if x != nil {
+		return x.Price
+	}
+	return nil
+}
+
+func (x *GetCurrentPriceResponse) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type GetScheduleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Zone  string                 `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	// From defaults to the start of today.
+	From *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	// To defaults to the end of the loaded prices.
+	To            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetScheduleRequest) Reset() {
+	*x = GetScheduleRequest{}
+	mi := &file_price_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetScheduleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetScheduleRequest) ProtoMessage() {}
+
+func (x *GetScheduleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetScheduleRequest.ProtoReflect.Descriptor instead.
+func (*GetScheduleRequest) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetScheduleRequest) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *GetScheduleRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *GetScheduleRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type GetScheduleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Zone          string                 `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	Prices        []*Price               `protobuf:"bytes,2,rep,name=prices,proto3" json:"prices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetScheduleResponse) Reset() {
+	*x = GetScheduleResponse{}
+	mi := &file_price_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetScheduleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetScheduleResponse) ProtoMessage() {}
+
+func (x *GetScheduleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetScheduleResponse.ProtoReflect.Descriptor instead.
+func (*GetScheduleResponse) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetScheduleResponse) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *GetScheduleResponse) GetPrices() []*Price {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+type FindCheapestWindowRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Zone     string                 `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	Duration *durationpb.Duration   `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	// Earliest defaults to now.
+	Earliest *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=earliest,proto3" json:"earliest,omitempty"`
+	// Deadline defaults to the end of the loaded prices.
+	Deadline *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	// Profile is the power draw in kW for equally long steps of the run,
+	// a constant 1 kW if empty.
+	Profile       []float64 `protobuf:"fixed64,5,rep,packed,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindCheapestWindowRequest) Reset() {
+	*x = FindCheapestWindowRequest{}
+	mi := &file_price_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindCheapestWindowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindCheapestWindowRequest) ProtoMessage() {}
+
+func (x *FindCheapestWindowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindCheapestWindowRequest.ProtoReflect.Descriptor instead.
+func (*FindCheapestWindowRequest) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FindCheapestWindowRequest) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *FindCheapestWindowRequest) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowRequest) GetEarliest() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Earliest
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowRequest) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowRequest) GetProfile() []float64 {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type PlanInterval struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	Cost          float64                `protobuf:"fixed64,3,opt,name=cost,proto3" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlanInterval) Reset() {
+	*x = PlanInterval{}
+	mi := &file_price_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanInterval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanInterval) ProtoMessage() {}
+
+func (x *PlanInterval) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanInterval.ProtoReflect.Descriptor instead.
+func (*PlanInterval) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PlanInterval) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *PlanInterval) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *PlanInterval) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+// FindCheapestWindowResponse holds the cheapest contiguous run and the
+// cheapest set of intervals, costs are in SEK.
+type FindCheapestWindowResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	Cost          float64                `protobuf:"fixed64,3,opt,name=cost,proto3" json:"cost,omitempty"`
+	Intervals     []*PlanInterval        `protobuf:"bytes,4,rep,name=intervals,proto3" json:"intervals,omitempty"`
+	IntervalsCost float64                `protobuf:"fixed64,5,opt,name=intervals_cost,json=intervalsCost,proto3" json:"intervals_cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FindCheapestWindowResponse) Reset() {
+	*x = FindCheapestWindowResponse{}
+	mi := &file_price_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FindCheapestWindowResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindCheapestWindowResponse) ProtoMessage() {}
+
+func (x *FindCheapestWindowResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindCheapestWindowResponse.ProtoReflect.Descriptor instead.
+func (*FindCheapestWindowResponse) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *FindCheapestWindowResponse) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowResponse) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowResponse) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *FindCheapestWindowResponse) GetIntervals() []*PlanInterval {
+	if x != nil {
+		return x.Intervals
+	}
+	return nil
+}
+
+func (x *FindCheapestWindowResponse) GetIntervalsCost() float64 {
+	if x != nil {
+		return x.IntervalsCost
+	}
+	return 0
+}
+
+type WatchPricesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Zones to watch, all configured zones if empty.
+	Zones         []string `protobuf:"bytes,1,rep,name=zones,proto3" json:"zones,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPricesRequest) Reset() {
+	*x = WatchPricesRequest{}
+	mi := &file_price_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPricesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPricesRequest) ProtoMessage() {}
+
+func (x *WatchPricesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPricesRequest.ProtoReflect.Descriptor instead.
+func (*WatchPricesRequest) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchPricesRequest) GetZones() []string {
+	if x != nil {
+		return x.Zones
+	}
+	return nil
+}
+
+type WatchPricesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Zone  string                 `protobuf:"bytes,2,opt,name=zone,proto3" json:"zone,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*WatchPricesResponse_PriceChanged
+	//	*WatchPricesResponse_TomorrowPublished
+	//	*WatchPricesResponse_Stale
+	Event         isWatchPricesResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPricesResponse) Reset() {
+	*x = WatchPricesResponse{}
+	mi := &file_price_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPricesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPricesResponse) ProtoMessage() {}
+
+func (x *WatchPricesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPricesResponse.ProtoReflect.Descriptor instead.
+func (*WatchPricesResponse) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchPricesResponse) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WatchPricesResponse) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *WatchPricesResponse) GetEvent() isWatchPricesResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *WatchPricesResponse) GetPriceChanged() *PriceChanged {
+	if x != nil {
+		if x, ok := x.Event.(*WatchPricesResponse_PriceChanged); ok {
+			return x.PriceChanged
+		}
+	}
+	return nil
+}
+
+func (x *WatchPricesResponse) GetTomorrowPublished() *TomorrowPublished {
+	if x != nil {
+		if x, ok := x.Event.(*WatchPricesResponse_TomorrowPublished); ok {
+			return x.TomorrowPublished
+		}
+	}
+	return nil
+}
+
+func (x *WatchPricesResponse) GetStale() *Stale {
+	if x != nil {
+		if x, ok := x.Event.(*WatchPricesResponse_Stale); ok {
+			return x.Stale
+		}
+	}
+	return nil
+}
+
+type isWatchPricesResponse_Event interface {
+	isWatchPricesResponse_Event()
+}
+
+type WatchPricesResponse_PriceChanged struct {
+	// PriceChanged is sent when the active price interval changes.
+	PriceChanged *PriceChanged `protobuf:"bytes,3,opt,name=price_changed,json=priceChanged,proto3,oneof"`
+}
+
+type WatchPricesResponse_TomorrowPublished struct {
+	// TomorrowPublished is sent when tomorrow's prices are loaded.
+	TomorrowPublished *TomorrowPublished `protobuf:"bytes,4,opt,name=tomorrow_published,json=tomorrowPublished,proto3,oneof"`
+}
+
+type WatchPricesResponse_Stale struct {
+	// Stale is sent when the loaded prices stop or start covering the present.
+	Stale *Stale `protobuf:"bytes,5,opt,name=stale,proto3,oneof"`
+}
+
+func (*WatchPricesResponse_PriceChanged) isWatchPricesResponse_Event() {}
+
+func (*WatchPricesResponse_TomorrowPublished) isWatchPricesResponse_Event() {}
+
+func (*WatchPricesResponse_Stale) isWatchPricesResponse_Event() {}
+
+type PriceChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         *Price                 `protobuf:"bytes,1,opt,name=price,proto3" json:"price,omitempty"`
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceChanged) Reset() {
+	*x = PriceChanged{}
+	mi := &file_price_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceChanged) ProtoMessage() {}
+
+func (x *PriceChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceChanged.ProtoReflect.Descriptor instead.
+func (*PriceChanged) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PriceChanged) GetPrice() *Price {
+	if x != nil {
+		return x.Price
+	}
+	return nil
+}
+
+func (x *PriceChanged) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type TomorrowPublished struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prices        []*Price               `protobuf:"bytes,1,rep,name=prices,proto3" json:"prices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TomorrowPublished) Reset() {
+	*x = TomorrowPublished{}
+	mi := &file_price_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TomorrowPublished) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TomorrowPublished) ProtoMessage() {}
+
+func (x *TomorrowPublished) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TomorrowPublished.ProtoReflect.Descriptor instead.
+func (*TomorrowPublished) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TomorrowPublished) GetPrices() []*Price {
+	if x != nil {
+		return x.Prices
+	}
+	return nil
+}
+
+type Stale struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stale         bool                   `protobuf:"varint,1,opt,name=stale,proto3" json:"stale,omitempty"`
+	LoadedAt      *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stale) Reset() {
+	*x = Stale{}
+	mi := &file_price_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stale) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stale) ProtoMessage() {}
+
+func (x *Stale) ProtoReflect() protoreflect.Message {
+	mi := &file_price_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stale.ProtoReflect.Descriptor instead.
+func (*Stale) Descriptor() ([]byte, []int) {
+	return file_price_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Stale) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+func (x *Stale) GetLoadedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return nil
+}
+
+var File_price_proto protoreflect.FileDescriptor
+
+const file_price_proto_rawDesc = "" +
+	"\n" +
+	"\vprice.proto\x12\x0fprice2influx.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xcb\x01\n" +
+	"\x05Price\x12\x1e\n" +
+	"\vsek_per_kwh\x18\x01 \x01(\x01R\tsekPerKwh\x12\x1e\n" +
+	"\veur_per_kwh\x18\x02 \x01(\x01R\teurPerKwh\x12\x10\n" +
+	"\x03exr\x18\x03 \x01(\x01R\x03exr\x129\n" +
+	"\n" +
+	"time_start\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimeStart\x125\n" +
+	"\btime_end\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\atimeEnd\",\n" +
+	"\x16GetCurrentPriceRequest\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\"q\n" +
+	"\x17GetCurrentPriceResponse\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\x12,\n" +
+	"\x05price\x18\x02 \x01(\v2\x16.price2influx.v1.PriceR\x05price\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\"\x84\x01\n" +
+	"\x12GetScheduleRequest\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\x12.\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\"Y\n" +
+	"\x13GetScheduleResponse\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\x12.\n" +
+	"\x06prices\x18\x02 \x03(\v2\x16.price2influx.v1.PriceR\x06prices\"\xf0\x01\n" +
+	"\x19FindCheapestWindowRequest\x12\x12\n" +
+	"\x04zone\x18\x01 \x01(\tR\x04zone\x125\n" +
+	"\bduration\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\bduration\x126\n" +
+	"\bearliest\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bearliest\x126\n" +
+	"\bdeadline\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x18\n" +
+	"\aprofile\x18\x05 \x03(\x01R\aprofile\"\x82\x01\n" +
+	"\fPlanInterval\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x12\n" +
+	"\x04cost\x18\x03 \x01(\x01R\x04cost\"\xf4\x01\n" +
+	"\x1aFindCheapestWindowResponse\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x12\n" +
+	"\x04cost\x18\x03 \x01(\x01R\x04cost\x12;\n" +
+	"\tintervals\x18\x04 \x03(\v2\x1d.price2influx.v1.PlanIntervalR\tintervals\x12%\n" +
+	"\x0eintervals_cost\x18\x05 \x01(\x01R\rintervalsCost\"*\n" +
+	"\x12WatchPricesRequest\x12\x14\n" +
+	"\x05zones\x18\x01 \x03(\tR\x05zones\"\x8d\x02\n" +
+	"\x13WatchPricesResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04zone\x18\x02 \x01(\tR\x04zone\x12D\n" +
+	"\rprice_changed\x18\x03 \x01(\v2\x1d.price2influx.v1.PriceChangedH\x00R\fpriceChanged\x12S\n" +
+	"\x12tomorrow_published\x18\x04 \x01(\v2\".price2influx.v1.TomorrowPublishedH\x00R\x11tomorrowPublished\x12.\n" +
+	"\x05stale\x18\x05 \x01(\v2\x16.price2influx.v1.StaleH\x00R\x05staleB\a\n" +
+	"\x05event\"R\n" +
+	"\fPriceChanged\x12,\n" +
+	"\x05price\x18\x01 \x01(\v2\x16.price2influx.v1.PriceR\x05price\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\"C\n" +
+	"\x11TomorrowPublished\x12.\n" +
+	"\x06prices\x18\x01 \x03(\v2\x16.price2influx.v1.PriceR\x06prices\"V\n" +
+	"\x05Stale\x12\x14\n" +
+	"\x05stale\x18\x01 \x01(\bR\x05stale\x127\n" +
+	"\tloaded_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\bloadedAt2\x99\x03\n" +
+	"\fPriceService\x12d\n" +
+	"\x0fGetCurrentPrice\x12'.price2influx.v1.GetCurrentPriceRequest\x1a(.price2influx.v1.GetCurrentPriceResponse\x12X\n" +
+	"\vGetSchedule\x12#.price2influx.v1.GetScheduleRequest\x1a$.price2influx.v1.GetScheduleResponse\x12m\n" +
+	"\x12FindCheapestWindow\x12*.price2influx.v1.FindCheapestWindowRequest\x1a+.price2influx.v1.FindCheapestWindowResponse\x12Z\n" +
+	"\vWatchPrices\x12#.price2influx.v1.WatchPricesRequest\x1a$.price2influx.v1.WatchPricesResponse0\x01B\x16Z\x14price2influx/pricepbb\x06proto3"
+
+var (
+	file_price_proto_rawDescOnce sync.Once
+	file_price_proto_rawDescData []byte
+)
+
+func file_price_proto_rawDescGZIP() []byte {
+	file_price_proto_rawDescOnce.Do(func() {
+		file_price_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_price_proto_rawDesc), len(file_price_proto_rawDesc)))
+	})
+	return file_price_proto_rawDescData
+}
+
+var file_price_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_price_proto_goTypes = []any{
+	(*Price)(nil),                      // 0: price2influx.v1.Price
+	(*GetCurrentPriceRequest)(nil),     // 1: price2influx.v1.GetCurrentPriceRequest
+	(*GetCurrentPriceResponse)(nil),    // 2: price2influx.v1.GetCurrentPriceResponse
+	(*GetScheduleRequest)(nil),         // 3: price2influx.v1.GetScheduleRequest
+	(*GetScheduleResponse)(nil),        // 4: price2influx.v1.GetScheduleResponse
+	(*FindCheapestWindowRequest)(nil),  // 5: price2influx.v1.FindCheapestWindowRequest
+	(*PlanInterval)(nil),               // 6: price2influx.v1.PlanInterval
+	(*FindCheapestWindowResponse)(nil), // 7: price2influx.v1.FindCheapestWindowResponse
+	(*WatchPricesRequest)(nil),         // 8: price2influx.v1.WatchPricesRequest
+	(*WatchPricesResponse)(nil),        // 9: price2influx.v1.WatchPricesResponse
+	(*PriceChanged)(nil),               // 10: price2influx.v1.PriceChanged
+	(*TomorrowPublished)(nil),          // 11: price2influx.v1.TomorrowPublished
+	(*Stale)(nil),                      // 12: price2influx.v1.Stale
+	(*timestamppb.Timestamp)(nil),      // 13: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),        // 14: google.protobuf.Duration
+}
+var file_price_proto_depIdxs = []int32{
+	13, // 0: price2influx.v1.Price.time_start:type_name -> google.protobuf.Timestamp
+	13, // 1: price2influx.v1.Price.time_end:type_name -> google.protobuf.Timestamp
+	0,  // 2: price2influx.v1.GetCurrentPriceResponse.price:type_name -> price2influx.v1.Price
+	13, // 3: price2influx.v1.GetScheduleRequest.from:type_name -> google.protobuf.Timestamp
+	13, // 4: price2influx.v1.GetScheduleRequest.to:type_name -> google.protobuf.Timestamp
+	0,  // 5: price2influx.v1.GetScheduleResponse.prices:type_name -> price2influx.v1.Price
+	14, // 6: price2influx.v1.FindCheapestWindowRequest.duration:type_name -> google.protobuf.Duration
+	13, // 7: price2influx.v1.FindCheapestWindowRequest.earliest:type_name -> google.protobuf.Timestamp
+	13, // 8: price2influx.v1.FindCheapestWindowRequest.deadline:type_name -> google.protobuf.Timestamp
+	13, // 9: price2influx.v1.PlanInterval.start:type_name -> google.protobuf.Timestamp
+	13, // 10: price2influx.v1.PlanInterval.end:type_name -> google.protobuf.Timestamp
+	13, // 11: price2influx.v1.FindCheapestWindowResponse.start:type_name -> google.protobuf.Timestamp
+	13, // 12: price2influx.v1.FindCheapestWindowResponse.end:type_name -> google.protobuf.Timestamp
+	6,  // 13: price2influx.v1.FindCheapestWindowResponse.intervals:type_name -> price2influx.v1.PlanInterval
+	10, // 14: price2influx.v1.WatchPricesResponse.price_changed:type_name -> price2influx.v1.PriceChanged
+	11, // 15: price2influx.v1.WatchPricesResponse.tomorrow_published:type_name -> price2influx.v1.TomorrowPublished
+	12, // 16: price2influx.v1.WatchPricesResponse.stale:type_name -> price2influx.v1.Stale
+	0,  // 17: price2influx.v1.PriceChanged.price:type_name -> price2influx.v1.Price
+	0,  // 18: price2influx.v1.TomorrowPublished.prices:type_name -> price2influx.v1.Price
+	13, // 19: price2influx.v1.Stale.loaded_at:type_name -> google.protobuf.Timestamp
+	1,  // 20: price2influx.v1.PriceService.GetCurrentPrice:input_type -> price2influx.v1.GetCurrentPriceRequest
+	3,  // 21: price2influx.v1.PriceService.GetSchedule:input_type -> price2influx.v1.GetScheduleRequest
+	5,  // 22: price2influx.v1.PriceService.FindCheapestWindow:input_type -> price2influx.v1.FindCheapestWindowRequest
+	8,  // 23: price2influx.v1.PriceService.WatchPrices:input_type -> price2influx.v1.WatchPricesRequest
+	2,  // 24: price2influx.v1.PriceService.GetCurrentPrice:output_type -> price2influx.v1.GetCurrentPriceResponse
+	4,  // 25: price2influx.v1.PriceService.GetSchedule:output_type -> price2influx.v1.GetScheduleResponse
+	7,  // 26: price2influx.v1.PriceService.FindCheapestWindow:output_type -> price2influx.v1.FindCheapestWindowResponse
+	9,  // 27: price2influx.v1.PriceService.WatchPrices:output_type -> price2influx.v1.WatchPricesResponse
+	24, // [24:28] is the sub-list for method output_type
+	20, // [20:24] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_price_proto_init() }
+func file_price_proto_init() {
+	if File_price_proto != nil {
+		return
+	}
+	file_price_proto_msgTypes[9].OneofWrappers = []any{
+		(*WatchPricesResponse_PriceChanged)(nil),
+		(*WatchPricesResponse_TomorrowPublished)(nil),
+		(*WatchPricesResponse_Stale)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_price_proto_rawDesc), len(file_price_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_price_proto_goTypes,
+		DependencyIndexes: file_price_proto_depIdxs,
+		MessageInfos:      file_price_proto_msgTypes,
+	}.Build()
+	File_price_proto = out.File
+	file_price_proto_goTypes = nil
+	file_price_proto_depIdxs = nil
+}
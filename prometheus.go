@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var metricsAddr = flag.String("metricsaddr", "", "Address for the Prometheus /metrics endpoint to listen on, disabled if empty")
+
+var (
+	priceDesc = prometheus.NewDesc("price2influx_price",
+		"Price per kWh of the active interval.", []string{"zone", "currency"}, nil)
+	nextPriceDesc = prometheus.NewDesc("price2influx_next_price",
+		"Price per kWh of the next interval.", []string{"zone", "currency"}, nil)
+	dayMinDesc = prometheus.NewDesc("price2influx_day_min_price",
+		"Lowest price per kWh of the day.", []string{"zone", "currency"}, nil)
+	dayMaxDesc = prometheus.NewDesc("price2influx_day_max_price",
+		"Highest price per kWh of the day.", []string{"zone", "currency"}, nil)
+	dayAvgDesc = prometheus.NewDesc("price2influx_day_avg_price",
+		"Time weighted average price per kWh of the day.", []string{"zone", "currency"}, nil)
+	loadedDesc = prometheus.NewDesc("price2influx_last_load_timestamp_seconds",
+		"Unix time today's prices were last loaded.", []string{"zone"}, nil)
+	dataAgeDesc = prometheus.NewDesc("price2influx_data_age_seconds",
+		"Seconds since today's prices were last loaded.", []string{"zone"}, nil)
+	dataEndDesc = prometheus.NewDesc("price2influx_data_end_timestamp_seconds",
+		"Unix time the last loaded price interval ends.", []string{"zone"}, nil)
+)
+
+// priceCollector exports the state of the price clients on every scrape.
+type priceCollector struct {
+	clients PriceClients
+}
+
+func (c priceCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{priceDesc, nextPriceDesc, dayMinDesc, dayMaxDesc, dayAvgDesc, loadedDesc, dataAgeDesc, dataEndDesc} {
+		ch <- d
+	}
+}
+
+func (c priceCollector) Collect(ch chan<- prometheus.Metric) {
+	now := clockSourceNow()
+	for _, pc := range c.clients {
+		zone := pc.Zone()
+		current, currentErr := pc.CurrentPrice(context.Background())
+		next, nextErr := pc.NextPrice()
+		today := pc.Today()
+		for _, currency := range currencies {
+			if currentErr == nil {
+				ch <- prometheus.MustNewConstMetric(priceDesc, prometheus.GaugeValue, current.PerkWh(currency), zone, currency)
+			}
+			if nextErr == nil {
+				ch <- prometheus.MustNewConstMetric(nextPriceDesc, prometheus.GaugeValue, next.PerkWh(currency), zone, currency)
+			}
+			if len(today) > 0 {
+				stats := today.Stats(currency)
+				ch <- prometheus.MustNewConstMetric(dayMinDesc, prometheus.GaugeValue, stats.Min, zone, currency)
+				ch <- prometheus.MustNewConstMetric(dayMaxDesc, prometheus.GaugeValue, stats.Max, zone, currency)
+				ch <- prometheus.MustNewConstMetric(dayAvgDesc, prometheus.GaugeValue, stats.Avg, zone, currency)
+			}
+		}
+		if loaded := pc.LoadedAt(); !loaded.IsZero() {
+			ch <- prometheus.MustNewConstMetric(loadedDesc, prometheus.GaugeValue, float64(loaded.Unix()), zone)
+			ch <- prometheus.MustNewConstMetric(dataAgeDesc, prometheus.GaugeValue, now.Sub(loaded).Seconds(), zone)
+		}
+		if schedule := pc.Schedule(context.Background()); len(schedule) > 0 {
+			ch <- prometheus.MustNewConstMetric(dataEndDesc, prometheus.GaugeValue, float64(schedule[len(schedule)-1].TimeEnd.Unix()), zone)
+		}
+	}
+}
+
+// newMetricsHandler returns the Prometheus /metrics endpoint served on
+// -metricsaddr, the prices and the self-instrumentation metrics.
+func newMetricsHandler(pcs PriceClients) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(priceCollector{clients: pcs})
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{reg, internalMetrics}, promhttp.HandlerOpts{}))
+	return mux
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	pc := loadedPriceClient(t)
+	pc.loadedAt = time.Date(2025, 2, 2, 10, 0, 0, 0, locale)
+	ts := httptest.NewServer(newMetricsHandler(PriceClients{pc}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`price2influx_price{currency="SEK",zone="SE3"} 0.78352`,
+		`price2influx_price{currency="EUR",zone="SE3"} 0.06814`,
+		`price2influx_next_price{currency="SEK",zone="SE3"} 0.78628`,
+		`price2influx_day_min_price{currency="SEK",zone="SE3"} 0.34059`,
+		`price2influx_day_max_price{currency="SEK",zone="SE3"} 1.14251`,
+		`price2influx_data_age_seconds{zone="SE3"} 1800`,
+		`price2influx_data_end_timestamp_seconds{zone="SE3"} 1.7386236e+09`,
+		`# TYPE price2influx_goroutines gauge`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var questDBAddr = flag.String("questdb-addr", "", "ILP over TCP address, e.g. QuestDB on localhost:9009, disabled if empty")
+var questDBKeyID = flag.String("questdb-keyid", "", "ILP auth key id, the handshake is skipped if empty")
+var questDBKey = flag.String("questdb-key", "", "ILP auth private key, the base64url encoded d of the ECDSA P-256 JWK")
+var questDBFlushSize = flag.Int("questdb-flushsize", 64<<10, "Buffered ILP bytes that trigger a flush")
+var questDBFlushInterval = flag.Duration("questdb-flushinterval", time.Second, "Longest time ILP lines are buffered before being flushed")
+
+// ilpTimeout bounds connecting to the server and each flush.
+const ilpTimeout = 10 * time.Second
+
+// maxILPBuffer bounds the lines kept while the server is unreachable, the
+// oldest lines are dropped beyond it.
+const maxILPBuffer = 1 << 20
+
+// questDBSink writes InfluxDB line protocol (ILP) with nanosecond
+// timestamps over a persistent TCP connection, as accepted by QuestDB. Lines
+// are buffered and flushed when the buffer reaches flushSize or
+// flushInterval has passed, reconnecting on failure. While flushes fail
+// every Write flushes and returns the error, the lines stay buffered.
+type questDBSink struct {
+	addr          string
+	keyID         string
+	key           *ecdsa.PrivateKey
+	flushSize     int
+	flushInterval time.Duration
+	tariff        *Tariff
+
+	mu   sync.Mutex
+	conn net.Conn
+	buf  []byte
+	// flushErr is the error of the last flush.
+	flushErr error
+	done     chan struct{}
+}
+
+func newQuestDBSink(addr string, tariff *Tariff) (*questDBSink, error) {
+	if *questDBFlushInterval <= 0 {
+		return nil, fmt.Errorf("questdb-flushinterval must be positive")
+	}
+	s := &questDBSink{
+		addr:          addr,
+		keyID:         *questDBKeyID,
+		flushSize:     *questDBFlushSize,
+		flushInterval: *questDBFlushInterval,
+		tariff:        tariff,
+		done:          make(chan struct{}),
+	}
+	if s.keyID != "" {
+		key, err := parseILPKey(*questDBKey)
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+	}
+	go s.flusher()
+	return s, nil
+}
+
+// parseILPKey returns the P-256 private key with the base64url encoded d.
+func parseILPKey(d string) (*ecdsa.PrivateKey, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(d, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ILP auth key: %v", err)
+	}
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(b)}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(b)
+	return key, nil
+}
+
+func (s *questDBSink) Name() string {
+	return "questdb"
+}
+
+func (s *questDBSink) Write(ctx context.Context, samples []Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, encodeLines(samples, s.tariff, time.Nanosecond)...)
+	if len(s.buf) > maxILPBuffer {
+		drop := len(s.buf) - maxILPBuffer
+		if i := strings.IndexByte(string(s.buf[drop:]), '\n'); i >= 0 {
+			drop += i + 1
+		}
+		slog.Warn("ILP buffer full, dropping oldest lines", "sink", s.Name(), "bytes", drop)
+		s.buf = s.buf[drop:]
+	}
+	if len(s.buf) >= s.flushSize || s.flushErr != nil {
+		return s.flush(ctx)
+	}
+	return nil
+}
+
+// flusher flushes the buffer every flushInterval until Close.
+func (s *questDBSink) flusher() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ilpTimeout)
+			s.mu.Lock()
+			if err := s.flush(ctx); err != nil {
+				slog.Error("Flushing ILP failed", "sink", s.Name(), "addr", s.addr, "error", err)
+			}
+			s.mu.Unlock()
+			cancel()
+		}
+	}
+}
+
+// flush writes the buffer by the deadline of ctx, connecting first if
+// needed. The buffer is kept for the next flush if the write fails.
+func (s *questDBSink) flush(ctx context.Context) (err error) {
+	defer func() { s.flushErr = err }()
+	if len(s.buf) == 0 {
+		return nil
+	}
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(ilpDeadline(ctx))
+	n, err := s.conn.Write(s.buf)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+		// Resend from the start of the partially written line.
+		if i := strings.LastIndexByte(string(s.buf[:n]), '\n'); i >= 0 {
+			s.buf = s.buf[i+1:]
+		}
+		return err
+	}
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// ilpDeadline returns the deadline of ctx, at most ilpTimeout from now.
+func ilpDeadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(ilpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// dial connects and performs the auth handshake if a key id is set: the key
+// id is sent, and the challenge line returned by the server is answered
+// with its base64 encoded ECDSA SHA-256 signature.
+func (s *questDBSink) dial(ctx context.Context) (net.Conn, error) {
+	conn, err := (&net.Dialer{Deadline: ilpDeadline(ctx)}).DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.keyID == "" {
+		return conn, nil
+	}
+	conn.SetDeadline(ilpDeadline(ctx))
+	if _, err := fmt.Fprintf(conn, "%s\n", s.keyID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	challenge, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error reading ILP auth challenge: %v", err)
+	}
+	hash := sha256.Sum256(challenge[:len(challenge)-1])
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, hash[:])
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", base64.StdEncoding.EncodeToString(sig)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// Close flushes the buffer and closes the connection.
+func (s *questDBSink) Close() error {
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), ilpTimeout)
+	defer cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.flush(ctx)
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ilpServer accepts ILP connections and sends every received line on lines.
+// If key is set, connections must pass the auth handshake first.
+type ilpServer struct {
+	ln    net.Listener
+	lines chan string
+	conns chan net.Conn
+	key   *ecdsa.PublicKey
+}
+
+func newILPServer(t *testing.T, key *ecdsa.PublicKey) *ilpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ilpServer{ln: ln, lines: make(chan string, 100), conns: make(chan net.Conn, 10), key: key}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- conn
+			go s.serve(t, conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *ilpServer) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if s.key != nil {
+		keyID, _ := r.ReadString('\n')
+		if keyID != "testkey\n" {
+			t.Errorf("key id got = %q, want = testkey", keyID)
+			return
+		}
+		challenge := "challenge123"
+		conn.Write([]byte(challenge + "\n"))
+		line, _ := r.ReadString('\n')
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(line, "\n"))
+		hash := sha256.Sum256([]byte(challenge))
+		if err != nil || !ecdsa.VerifyASN1(s.key, hash[:], sig) {
+			t.Errorf("invalid signature %q", line)
+			return
+		}
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		s.lines <- strings.TrimSuffix(line, "\n")
+	}
+}
+
+func (s *ilpServer) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case line := <-s.lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ILP line")
+		return ""
+	}
+}
+
+func TestQuestDBSink(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := base64.RawURLEncoding.EncodeToString(key.D.Bytes())
+	sample := Sample{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)}
+	want := "price,currency=SEK price=0.5 1738450800000000000"
+
+	tests := []struct {
+		name      string
+		auth      bool
+		flushSize int
+		interval  time.Duration
+	}{
+		{name: "flush on size", flushSize: 1, interval: time.Hour},
+		{name: "flush on interval", flushSize: 1 << 20, interval: 10 * time.Millisecond},
+		{name: "auth", auth: true, flushSize: 1, interval: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pub *ecdsa.PublicKey
+			if tt.auth {
+				pub = &key.PublicKey
+			}
+			server := newILPServer(t, pub)
+			questDBKeyID, questDBKey = new(string), &d
+			if tt.auth {
+				*questDBKeyID = "testkey"
+			}
+			questDBFlushSize, questDBFlushInterval = &tt.flushSize, &tt.interval
+			sink, err := newQuestDBSink(server.ln.Addr().String(), nil)
+			if err != nil {
+				t.Fatalf("newQuestDBSink() error = %v", err)
+			}
+			defer sink.Close()
+
+			if err := sink.Write(context.Background(), []Sample{sample}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := server.next(t); got != want {
+				t.Errorf("line got = %q, want = %q", got, want)
+			}
+		})
+	}
+}
+
+func TestQuestDBSinkReconnect(t *testing.T) {
+	server := newILPServer(t, nil)
+	sink := &questDBSink{addr: server.ln.Addr().String(), flushSize: 1, done: make(chan struct{})}
+	sample := Sample{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)}
+	ctx := context.Background()
+	if err := sink.Write(ctx, []Sample{sample}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	server.next(t)
+
+	// Drop the connection. Writes into the dead connection are lost until
+	// the sink notices, the failed write is kept and resent on reconnect.
+	(<-server.conns).Close()
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		err = sink.Write(ctx, []Sample{sample})
+	}
+	if err == nil {
+		t.Fatal("Write() to a closed connection error = nil")
+	}
+	if err := sink.Write(ctx, []Sample{sample}); err != nil {
+		t.Fatalf("Write() after reconnect error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if got := server.next(t); !strings.HasPrefix(got, "price,") {
+			t.Errorf("line got = %q after reconnect", got)
+		}
+	}
+}
+
+func TestQuestDBSinkFlushErrors(t *testing.T) {
+	defer func(size int, interval time.Duration) {
+		*questDBFlushSize, *questDBFlushInterval = size, interval
+	}(*questDBFlushSize, *questDBFlushInterval)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		*questDBFlushInterval = interval
+		if _, err := newQuestDBSink("localhost:9009", nil); err == nil {
+			t.Errorf("newQuestDBSink() with flush interval %s error = nil", interval)
+		}
+	}
+
+	// Nothing listens on the address once the listener is closed.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	*questDBFlushSize, *questDBFlushInterval = 1<<20, 10*time.Millisecond
+	sink, err := newQuestDBSink(ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("newQuestDBSink() error = %v", err)
+	}
+	defer sink.Close()
+	sample := Sample{Zone: "SE3", Price: Price{SEKPerkWh: 0.5}, Time: time.Unix(1738450800, 0)}
+	if err := sink.Write(context.Background(), []Sample{sample}); err != nil {
+		t.Fatalf("buffered Write() error = %v", err)
+	}
+	// The failed background flush is returned by the next writes.
+	deadline := time.Now().Add(5 * time.Second)
+	for err == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		err = sink.Write(context.Background(), []Sample{sample})
+	}
+	if err == nil {
+		t.Errorf("Write() after a failed flush error = nil")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var remoteWriteURL = flag.String("remotewrite-url", "", "Prometheus remote-write endpoint, disabled if empty. One sample is sent per price interval, query with last_over_time as intervals exceed the 5m lookback")
+var remoteWriteUsername = flag.String("remotewrite-username", "", "Prometheus remote-write basic auth username")
+var remoteWritePassword = flag.String("remotewrite-password", "", "Prometheus remote-write basic auth password")
+var remoteWriteBearerToken = flag.String("remotewrite-bearertoken", "", "Prometheus remote-write bearer token")
+var remoteWriteRetries = flag.Int("remotewrite-retries", 3, "Prometheus remote-write retries for failed requests")
+var remoteWriteBatchSize = flag.Int("remotewrite-batchsize", 500, "Prometheus remote-write max series per request")
+
+// remoteWriteSink pushes samples to a Prometheus remote-write endpoint,
+// timestamped at the start of their interval. Every interval is sent once
+// per zone, so with price intervals longer than the 5m lookback of the
+// Prometheus query engine an instant query sees the series as stale for the
+// rest of the interval. Query it with last_over_time, e.g.
+// last_over_time(price2influx_price[1h]), or raise --query.lookback-delta.
+type remoteWriteSink struct {
+	url         string
+	username    string
+	password    string
+	bearerToken string
+	retries     int
+	batchSize   int
+	backoff     time.Duration
+	tariff      *Tariff
+	client      *http.Client
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+func newRemoteWriteSink(url string, tariff *Tariff) *remoteWriteSink {
+	return &remoteWriteSink{
+		url:         url,
+		username:    *remoteWriteUsername,
+		password:    *remoteWritePassword,
+		bearerToken: *remoteWriteBearerToken,
+		retries:     *remoteWriteRetries,
+		batchSize:   *remoteWriteBatchSize,
+		backoff:     time.Second,
+		tariff:      tariff,
+		client:      &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+	}
+}
+
+type promLabel struct {
+	name, value string
+}
+
+// promSeries is a time series with a single sample, labels sorted by name.
+type promSeries struct {
+	labels    []promLabel
+	value     float64
+	timestamp int64
+}
+
+func (s *remoteWriteSink) Name() string {
+	return "remote-write"
+}
+
+func (s *remoteWriteSink) Write(ctx context.Context, samples []Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sent == nil {
+		s.sent = map[string]time.Time{}
+	}
+
+	var pending []Sample
+	var series []promSeries
+	for _, sample := range samples {
+		if s.sent[sample.Zone].Equal(sample.Price.TimeStart) {
+			continue
+		}
+		pending = append(pending, sample)
+		series = append(series, s.series(sample)...)
+	}
+
+	batchSize := s.batchSize
+	if batchSize <= 0 {
+		batchSize = len(series)
+	}
+	for len(series) > 0 {
+		n := min(batchSize, len(series))
+		body := snappy.Encode(nil, encodeWriteRequest(series[:n]))
+		if err := s.send(ctx, body); err != nil {
+			return err
+		}
+		series = series[n:]
+	}
+	for _, sample := range pending {
+		s.sent[sample.Zone] = sample.Price.TimeStart
+	}
+	return nil
+}
+
+func (s *remoteWriteSink) series(sample Sample) []promSeries {
+	ts := sample.Price.TimeStart.UnixMilli()
+	series := make([]promSeries, 0, len(currencies)+1)
+	for _, currency := range currencies {
+		if !sample.Selected("price") {
+			break
+		}
+		series = append(series, promSeries{
+			labels:    []promLabel{{"__name__", "price2influx_price"}, {"currency", currency}, {"zone", sample.Zone}},
+			value:     sample.Price.PerkWh(currency),
+			timestamp: ts,
+		})
+	}
+	if s.tariff != nil && sample.Selected("total_price") {
+		series = append(series, promSeries{
+			labels:    []promLabel{{"__name__", "price2influx_total_price"}, {"currency", "SEK"}, {"zone", sample.Zone}},
+			value:     s.tariff.Breakdown(sample.Price).Total,
+			timestamp: ts,
+		})
+	}
+	return series
+}
+
+// send posts the request, retrying with exponential backoff on network
+// errors, 5xx and 429 responses.
+func (s *remoteWriteSink) send(ctx context.Context, body []byte) error {
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%v, last error: %v", ctx.Err(), err)
+			case <-time.After(s.backoff << (attempt - 1)):
+			}
+		}
+		var retry bool
+		retry, err = s.post(ctx, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (s *remoteWriteSink) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "price2influx")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	switch {
+	case s.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken)
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("remote write returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest protobuf message:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(series []promSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
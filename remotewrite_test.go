@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoFields calls f with the number and the raw value of every field of
+// the protobuf message b, the varint and fixed64 values decoded into n.
+func protoFields(t *testing.T, b []byte, f func(num protowire.Number, n uint64, v []byte)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			t.Fatalf("error decoding tag: %v", protowire.ParseError(l))
+		}
+		b = b[l:]
+		var n uint64
+		var v []byte
+		switch typ {
+		case protowire.VarintType:
+			n, l = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			n, l = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, l = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %v of field %d", typ, num)
+		}
+		if l < 0 {
+			t.Fatalf("error decoding field %d: %v", num, protowire.ParseError(l))
+		}
+		b = b[l:]
+		f(num, n, v)
+	}
+}
+
+// decodeWriteRequest decodes the body as a prometheus.WriteRequest, see
+// encodeWriteRequest for the schema.
+func decodeWriteRequest(t *testing.T, b []byte) []promSeries {
+	t.Helper()
+	var series []promSeries
+	protoFields(t, b, func(num protowire.Number, _ uint64, ts []byte) {
+		if num != 1 {
+			t.Fatalf("unexpected WriteRequest field %d", num)
+		}
+		var s promSeries
+		samples := 0
+		protoFields(t, ts, func(num protowire.Number, _ uint64, v []byte) {
+			switch num {
+			case 1:
+				var l promLabel
+				protoFields(t, v, func(num protowire.Number, _ uint64, v []byte) {
+					if num == 1 {
+						l.name = string(v)
+					} else {
+						l.value = string(v)
+					}
+				})
+				s.labels = append(s.labels, l)
+			case 2:
+				samples++
+				protoFields(t, v, func(num protowire.Number, n uint64, _ []byte) {
+					if num == 1 {
+						s.value = math.Float64frombits(n)
+					} else {
+						s.timestamp = int64(n)
+					}
+				})
+			}
+		})
+		if samples != 1 {
+			t.Fatalf("samples got = %d, want = 1", samples)
+		}
+		series = append(series, s)
+	})
+	return series
+}
+
+func TestRemoteWriteSink(t *testing.T) {
+	start := time.Date(2025, 2, 2, 10, 0, 0, 0, locale)
+	price := Price{SEKPerkWh: 0.78352, EURPerkWh: 0.06814, TimeStart: start, TimeEnd: start.Add(time.Hour)}
+	samples := []Sample{{Zone: "SE3", Price: price}, {Zone: "SE4", Price: price}}
+
+	var mu sync.Mutex
+	var got []promSeries
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// Fail the first request to exercise the retries.
+		if requests == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			t.Errorf("basic auth got = %q %q, want = user pass", user, pass)
+		}
+		if enc := r.Header.Get("Content-Encoding"); enc != "snappy" {
+			t.Errorf("Content-Encoding got = %q, want = snappy", enc)
+		}
+		compressed, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, decodeWriteRequest(t, body)...)
+	}))
+	defer ts.Close()
+
+	sink := &remoteWriteSink{
+		url:       ts.URL,
+		username:  "user",
+		password:  "pass",
+		retries:   1,
+		batchSize: 3,
+		backoff:   time.Millisecond,
+		client:    ts.Client(),
+	}
+	if err := sink.Write(context.Background(), samples); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+	// The same intervals are only sent once.
+	if err := sink.Write(context.Background(), samples); err != nil {
+		t.Fatalf("Write() error got = %v, want = nil", err)
+	}
+
+	ms := start.UnixMilli()
+	want := []promSeries{
+		{labels: []promLabel{{"__name__", "price2influx_price"}, {"currency", "SEK"}, {"zone", "SE3"}}, value: 0.78352, timestamp: ms},
+		{labels: []promLabel{{"__name__", "price2influx_price"}, {"currency", "EUR"}, {"zone", "SE3"}}, value: 0.06814, timestamp: ms},
+		{labels: []promLabel{{"__name__", "price2influx_price"}, {"currency", "SEK"}, {"zone", "SE4"}}, value: 0.78352, timestamp: ms},
+		{labels: []promLabel{{"__name__", "price2influx_price"}, {"currency", "EUR"}, {"zone", "SE4"}}, value: 0.06814, timestamp: ms},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(promSeries{}, promLabel{})); diff != "" {
+		t.Errorf("received series mismatch (-want +got):\n%s", diff)
+	}
+	// One failed request and two batches.
+	if requests != 3 {
+		t.Errorf("requests got = %d, want = 3", requests)
+	}
+}
+
+func TestRemoteWriteSinkClientError(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "out of bounds", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	sink := &remoteWriteSink{url: ts.URL, retries: 3, backoff: time.Millisecond, client: ts.Client()}
+	err := sink.Write(context.Background(), []Sample{{Zone: "SE3", Price: Price{TimeStart: time.Unix(0, 0)}}})
+	if err == nil {
+		t.Fatal("Write() error got = nil, want error")
+	}
+	if requests != 1 {
+		t.Errorf("requests got = %d, want = 1, 4xx responses must not be retried", requests)
+	}
+}
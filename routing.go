@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var sinksFile = flag.String("sinks", "", "JSON file with additional sinks, each with its own zones, fields, interval and failure policy, see sinks.example.json")
+
+// maxPendingBatches bounds the failed batches kept by the retry policy, the
+// oldest batches are dropped beyond it.
+const maxPendingBatches = 100
+
+// Duration is a time.Duration in JSON, e.g. "10s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// SinkConfig is an entry of the -sinks file. Addr, Token, Org and Bucket
+// override the flags of the type, other settings are taken from the flags:
+//
+//	Addr    the endpoint of every type: URL, host:port, broker or DSN
+//	Token   the token of influxdb and influxdb-v3, the bearer token of remotewrite
+//	Org     the organization of influxdb
+//	Bucket  the bucket of influxdb, the database of influxdb-v1 and
+//	        influxdb-v3, the table of postgres
+//
+// Setting a field the type does not use is an error.
+type SinkConfig struct {
+	Name string `json:"name"`
+	// Type is one of influxdb, influxdb-v1, influxdb-v3, remotewrite, mqtt,
+	// questdb or postgres.
+	Type   string `json:"type"`
+	Addr   string `json:"addr"`
+	Token  string `json:"token"`
+	Org    string `json:"org"`
+	Bucket string `json:"bucket"`
+
+	// Zones restricts the zones written, all zones if empty.
+	Zones []string `json:"zones"`
+	// Fields restricts the fields or MQTT sensors written, all if empty.
+	Fields   []string `json:"fields"`
+	Interval Duration `json:"interval"`
+	Timeout  Duration `json:"timeout"`
+	// OnFailure is drop, retry or disable.
+	OnFailure   string `json:"on_failure"`
+	MaxFailures int    `json:"max_failures"`
+}
+
+// sinkRoute writes the samples of the selected zones and fields to a sink
+// every interval, independently of the other routes.
+type sinkRoute struct {
+	name        string
+	sink        Sink
+	zones       []string
+	fields      []string
+	interval    time.Duration
+	timeout     time.Duration
+	onFailure   string
+	maxFailures int
+
+	paused atomic.Bool
+
+	// mu is held while writing to the sink.
+	mu      sync.Mutex
+	pending [][]Sample
+
+	// statusMu guards the status, it is never held while writing so a
+	// slow sink does not block the status.
+	statusMu  sync.Mutex
+	queued    int
+	failures  int
+	disabled  bool
+	lastWrite time.Time
+	lastErr   error
+}
+
+// RouteStatus is the state of a route reported by the admin API.
+type RouteStatus struct {
+	Name     string `json:"name"`
+	Paused   bool   `json:"paused"`
+	Disabled bool   `json:"disabled"`
+	Failures int    `json:"failures"`
+	Pending  int    `json:"pending_batches"`
+	// LastWrite is the last accepted write, LastError the last failure.
+	LastWrite time.Time `json:"last_write,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+func (r *sinkRoute) status() RouteStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	status := RouteStatus{Name: r.name, Paused: r.paused.Load(), Disabled: r.disabled, Failures: r.failures, Pending: r.queued, LastWrite: r.lastWrite}
+	if r.lastErr != nil {
+		status.LastError = r.lastErr.Error()
+	}
+	return status
+}
+
+// newSinkRoute returns a route of all zones and fields with the defaults of
+// the -influxupdaterate flag.
+func newSinkRoute(sink Sink) *sinkRoute {
+	return &sinkRoute{
+		name:        sink.Name(),
+		sink:        sink,
+		interval:    *influxInterval,
+		timeout:     *influxInterval - 500*time.Millisecond,
+		onFailure:   "drop",
+		maxFailures: 3,
+	}
+}
+
+// loadSinkConfigs reads the -sinks file.
+func loadSinkConfigs(path string) ([]SinkConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []SinkConfig
+	if err := json.Unmarshal(b, &configs); err != nil {
+		return nil, fmt.Errorf("error parsing sinks file %s: %v", path, err)
+	}
+	return configs, nil
+}
+
+// sinkConfigFields are the SinkConfig fields besides Addr used by each type.
+var sinkConfigFields = map[string][]string{
+	"influxdb":    {"token", "org", "bucket"},
+	"influxdb-v1": {"bucket"},
+	"influxdb-v3": {"token", "bucket"},
+	"remotewrite": {"token"},
+	"mqtt":        nil,
+	"questdb":     nil,
+	"postgres":    {"bucket"},
+}
+
+// newConfiguredRoute creates the sink of the config and its route.
+func newConfiguredRoute(c SinkConfig, pcs PriceClients, tariff *Tariff) (*sinkRoute, error) {
+	or := func(v, def string) string {
+		if v != "" {
+			return v
+		}
+		return def
+	}
+	used, ok := sinkConfigFields[c.Type]
+	if !ok {
+		return nil, fmt.Errorf("sink %q: unknown type %q", c.Name, c.Type)
+	}
+	for _, f := range []struct{ name, value string }{{"token", c.Token}, {"org", c.Org}, {"bucket", c.Bucket}} {
+		if f.value != "" && !slices.Contains(used, f.name) {
+			return nil, fmt.Errorf("sink %q: %s is not used by type %s", c.Name, f.name, c.Type)
+		}
+	}
+	var sink Sink
+	var err error
+	switch c.Type {
+	case "influxdb":
+		client := newInfluxClient(or(c.Addr, *influxAddr), or(c.Token, *influxToken))
+		sink = &influxSink{writeAPI: client.WriteAPIBlocking(or(c.Org, *influxOrg), or(c.Bucket, *influxBucket)), tariff: tariff}
+	case "influxdb-v1":
+		var s *influxV1Sink
+		s, err = newInfluxV1Sink(or(c.Addr, *influxV1Addr), tariff)
+		if err == nil {
+			s.db = or(c.Bucket, s.db)
+		}
+		sink = s
+	case "influxdb-v3":
+		var s *influxV3Sink
+		s, err = newInfluxV3Sink(or(c.Addr, *influxV3Addr), tariff)
+		if err == nil {
+			s.token = or(c.Token, s.token)
+			s.db = or(c.Bucket, s.db)
+		}
+		sink = s
+	case "remotewrite":
+		s := newRemoteWriteSink(or(c.Addr, *remoteWriteURL), tariff)
+		s.bearerToken = or(c.Token, s.bearerToken)
+		sink = s
+	case "mqtt":
+		sink = newMQTTSink(or(c.Name, "mqtt"), or(c.Addr, *mqttBroker), pcs, c.Zones, c.Fields)
+	case "questdb":
+		sink, err = newQuestDBSink(or(c.Addr, *questDBAddr), tariff)
+	case "postgres":
+		sink, err = newPostgresSink(context.Background(), or(c.Addr, *postgresDSN), or(c.Bucket, *postgresTable), pcs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %v", c.Name, err)
+	}
+
+	r := newSinkRoute(sink)
+	r.name = or(c.Name, r.name)
+	for _, zone := range c.Zones {
+		if !slices.Contains(priceClasses, zone) {
+			return nil, fmt.Errorf("sink %q: priceclass must be one of %v", r.name, priceClasses)
+		}
+	}
+	r.zones = c.Zones
+	r.fields = c.Fields
+	if c.Interval.Duration > 0 {
+		r.interval = c.Interval.Duration
+		r.timeout = c.Interval.Duration
+	}
+	if c.Timeout.Duration > 0 {
+		r.timeout = c.Timeout.Duration
+	}
+	switch c.OnFailure {
+	case "":
+	case "drop", "retry", "disable":
+		r.onFailure = c.OnFailure
+	default:
+		return nil, fmt.Errorf("sink %q: on_failure must be drop, retry or disable", r.name)
+	}
+	if c.MaxFailures > 0 {
+		r.maxFailures = c.MaxFailures
+	}
+	return r, nil
+}
+
+// filter returns the samples of the route's zones with its fields.
+func (r *sinkRoute) filter(samples []Sample) []Sample {
+	filtered := make([]Sample, 0, len(samples))
+	for _, sample := range samples {
+		if len(r.zones) > 0 && !slices.Contains(r.zones, sample.Zone) {
+			continue
+		}
+		sample.Fields = r.fields
+		filtered = append(filtered, sample)
+	}
+	return filtered
+}
+
+// observeWrite writes the batch to the sink, recording its latency and
+// errors.
+func (r *sinkRoute) observeWrite(ctx context.Context, batch []Sample) error {
+	return r.observe(ctx, len(batch), func(ctx context.Context) error {
+		return r.sink.Write(ctx, batch)
+	})
+}
+
+// observe runs the write of n samples or intervals, recording its latency
+// and errors.
+func (r *sinkRoute) observe(ctx context.Context, n int, write func(context.Context) error) (err error) {
+	ctx, span := tracer.Start(ctx, "sink.Write", trace.WithAttributes(sinkKey.String(r.name), samplesKey.Int(n)))
+	defer func() { endSpan(span, err) }()
+	inflight.WithLabelValues("write").Inc()
+	defer inflight.WithLabelValues("write").Dec()
+	start := time.Now()
+	err = write(ctx)
+	writeDuration.WithLabelValues(r.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		writeErrors.WithLabelValues(r.name).Inc()
+	}
+	return err
+}
+
+// backfill writes the intervals of the zone to the route if it routes the
+// zone, whole to an intervalSink and as samples timestamped at their start
+// to other sinks. The failure policy does not apply, errors are returned.
+func (r *sinkRoute) backfill(ctx context.Context, zone string, prices Prices) error {
+	if len(prices) == 0 || len(r.zones) > 0 && !slices.Contains(r.zones, zone) {
+		return nil
+	}
+	// Sinks are not written concurrently, wait for the route.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sink.(intervalSink); ok {
+		return r.observe(ctx, len(prices), func(ctx context.Context) error {
+			return s.UpsertPrices(ctx, zone, prices)
+		})
+	}
+	samples := make([]Sample, 0, len(prices))
+	for _, p := range prices {
+		samples = append(samples, Sample{Zone: zone, Price: p, Time: p.TimeStart})
+	}
+	return r.observeWrite(ctx, r.filter(samples))
+}
+
+// write writes the samples in a sinkRoute.write span, see writeTraced.
+func (r *sinkRoute) write(samples []Sample) bool {
+	ctx, span := tracer.Start(context.Background(), "sinkRoute.write", trace.WithAttributes(sinkKey.String(r.name)))
+	defer span.End()
+	return r.writeTraced(ctx, samples)
+}
+
+// writeTraced writes the samples in the span of ctx, applying the failure
+// policy. It reports false once the route is disabled.
+func (r *sinkRoute) writeTraced(ctx context.Context, samples []Sample) bool {
+	_, lock := tracer.Start(ctx, "sinkRoute.lock")
+	r.mu.Lock()
+	lock.End()
+	defer r.mu.Unlock()
+	defer func() {
+		r.statusMu.Lock()
+		r.queued = len(r.pending)
+		r.statusMu.Unlock()
+		queueDepth.WithLabelValues(r.name).Set(float64(len(r.pending)))
+	}()
+	_, filter := tracer.Start(ctx, "filterSamples")
+	batches := [][]Sample{r.filter(samples)}
+	filter.End()
+	if r.onFailure == "retry" {
+		batches = append(r.pending, batches[0])
+		r.pending = nil
+	}
+	for i, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		err := r.observeWrite(ctx, batch)
+		cancel()
+		failures := r.recordWrite(err)
+		if err == nil {
+			continue
+		}
+		r.logger().Error("Write failed", "error", err)
+		switch r.onFailure {
+		case "retry":
+			r.pending = batches[i:]
+			if len(r.pending) > maxPendingBatches {
+				r.logger().Warn("Dropping pending batches", "batches", len(r.pending)-maxPendingBatches)
+				r.pending = r.pending[len(r.pending)-maxPendingBatches:]
+			}
+			return true
+		case "disable":
+			if failures >= r.maxFailures {
+				r.logger().Error("Disabling sink", "failures", failures)
+				r.statusMu.Lock()
+				r.disabled = true
+				r.statusMu.Unlock()
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// run writes the samples returned by source every interval until ctx is
+// done. A slow sink only delays its own route, and nothing is written while
+// the route is paused or disabled. Reading the samples is traced in the
+// sinkRoute.write span, a write in progress is not cancelled by ctx.
+func (r *sinkRoute) run(ctx context.Context, source func(context.Context) []Sample) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.paused.Load() || r.isDisabled() {
+				continue
+			}
+			ctx, span := tracer.Start(context.WithoutCancel(ctx), "sinkRoute.write", trace.WithAttributes(sinkKey.String(r.name)))
+			r.writeTraced(ctx, source(ctx))
+			span.End()
+		}
+	}
+}
+
+// recordWrite records the result of a write in the status, returning the
+// failures in a row.
+func (r *sinkRoute) recordWrite(err error) int {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	if err != nil {
+		r.failures++
+		r.lastErr = err
+		return r.failures
+	}
+	r.failures = 0
+	r.lastWrite = clockSourceNow()
+	return 0
+}
+
+func (r *sinkRoute) isDisabled() bool {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.disabled
+}
+
+// resume unpauses the route and enables it again if the disable failure
+// policy disabled it.
+func (r *sinkRoute) resume() {
+	r.statusMu.Lock()
+	r.disabled = false
+	r.failures = 0
+	r.statusMu.Unlock()
+	r.paused.Store(false)
+}
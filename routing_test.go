@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordSink records the written batches, failing while err is set and
+// blocking until the context is done if block is set.
+type recordSink struct {
+	mu      sync.Mutex
+	err     error
+	block   bool
+	batches [][]Sample
+}
+
+func (s *recordSink) Name() string {
+	return "record"
+}
+
+func (s *recordSink) Write(ctx context.Context, samples []Sample) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, samples)
+	return nil
+}
+
+func (s *recordSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+func TestSinkRouteFilter(t *testing.T) {
+	r := &sinkRoute{zones: []string{"SE3"}, fields: []string{"price"}}
+	samples := []Sample{{Zone: "SE1"}, {Zone: "SE3"}}
+	want := []Sample{{Zone: "SE3", Fields: []string{"price"}}}
+	if diff := cmp.Diff(want, r.filter(samples)); diff != "" {
+		t.Errorf("filter() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSamplePoint(t *testing.T) {
+	tariff := &Tariff{VAT: Rates{{Value: 0.25}}}
+	sample := Sample{Zone: "SE3", Price: Price{SEKPerkWh: 1}, Time: time.Unix(1738450800, 0)}
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{name: "all", want: "price,currency=SEK price=1,total_price=1.25,energy_tax=0,markup=0,certificates=0,grid_fee=0,vat=0.25 1738450800\n"},
+		{name: "selected", fields: []string{"price", "total_price"}, want: "price,currency=SEK price=1,total_price=1.25 1738450800\n"},
+		{name: "none", fields: []string{"level"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample.Fields = tt.fields
+			if got := string(encodeLines([]Sample{sample}, tariff, time.Second)); got != tt.want {
+				t.Errorf("encodeLines() got = %q, want = %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkRouteFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		onFailure    string
+		wantBatches  int
+		wantDisabled bool
+	}{
+		{name: "drop", onFailure: "drop", wantBatches: 1},
+		{name: "retry", onFailure: "retry", wantBatches: 4},
+		{name: "disable", onFailure: "disable", wantDisabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordSink{err: errors.New("unavailable")}
+			r := &sinkRoute{name: "test", sink: sink, timeout: time.Second, onFailure: tt.onFailure, maxFailures: 3}
+			samples := []Sample{{Zone: "SE3"}}
+			disabled := false
+			for i := 0; i < 3; i++ {
+				disabled = disabled || !r.write(samples)
+			}
+			if disabled != tt.wantDisabled {
+				t.Fatalf("disabled got = %v, want = %v", disabled, tt.wantDisabled)
+			}
+			sink.err = nil
+			if !disabled {
+				r.write(samples)
+			}
+			if sink.count() != tt.wantBatches {
+				t.Errorf("batches got = %d, want = %d", sink.count(), tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestSinkRouteNonBlocking(t *testing.T) {
+	slow := &sinkRoute{name: "slow", sink: &recordSink{block: true}, interval: 5 * time.Millisecond, timeout: 100 * time.Millisecond, onFailure: "drop"}
+	fast := &recordSink{}
+	ctx, cancel := context.WithCancel(context.Background())
+	source := func(context.Context) []Sample { return []Sample{{Zone: "SE3"}} }
+	var wg sync.WaitGroup
+	for _, route := range []*sinkRoute{slow, {name: "fast", sink: fast, interval: 5 * time.Millisecond, timeout: time.Second, onFailure: "drop"}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			route.run(ctx, source)
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for fast.count() < 3 {
+		select {
+		case <-deadline:
+			t.Fatalf("fast sink got %d writes while the slow sink blocks", fast.count())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestNewConfiguredRoute(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sinks.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "se3", "type": "influxdb", "bucket": "longterm", "zones": ["SE3"], "fields": ["price"], "interval": "1m", "on_failure": "retry"},
+		{"type": "influxdb", "zones": ["SE9"]},
+		{"type": "carrier-pigeon"},
+		{"type": "questdb", "addr": "localhost:9009", "bucket": "prices"},
+		{"type": "influxdb-v3", "addr": "http://localhost:8181", "org": "my-org"}
+	]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := loadSinkConfigs(path)
+	if err != nil {
+		t.Fatalf("loadSinkConfigs() error = %v", err)
+	}
+	r, err := newConfiguredRoute(configs[0], nil, nil)
+	if err != nil {
+		t.Fatalf("newConfiguredRoute() error = %v", err)
+	}
+	if r.name != "se3" || r.interval != time.Minute || r.timeout != time.Minute || r.onFailure != "retry" ||
+		!cmp.Equal(r.zones, []string{"SE3"}) || !cmp.Equal(r.fields, []string{"price"}) {
+		t.Errorf("newConfiguredRoute() got = %+v", r)
+	}
+	for _, c := range configs[1:] {
+		if _, err := newConfiguredRoute(c, nil, nil); err == nil {
+			t.Errorf("newConfiguredRoute(%+v) error = nil, want error", c)
+		}
+	}
+	mqttRoute, err := newConfiguredRoute(SinkConfig{Name: "ha", Type: "mqtt", Addr: "tcp://127.0.0.1:1"}, nil, nil)
+	if err != nil {
+		t.Fatalf("newConfiguredRoute() error = %v", err)
+	}
+	client := mqttRoute.sink.(*mqttSink).client
+	defer client.Disconnect(0)
+	if opts := client.OptionsReader(); opts.ClientID() != "price2influx-ha" || opts.WillTopic() != "price2influx/status/ha" {
+		t.Errorf("MQTT client id got = %q, will topic = %q, want = price2influx-ha, price2influx/status/ha", opts.ClientID(), opts.WillTopic())
+	}
+	if _, err := loadSinkConfigs("sinks.example.json"); err != nil {
+		t.Errorf("loadSinkConfigs(sinks.example.json) error = %v", err)
+	}
+}
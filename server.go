@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// newHTTPHandler returns the HTTP API served on -httpaddr. The history
+// endpoints are only served if history is not nil, the admin endpoints if
+// admin is not nil, and the mirror of the upstream API if -mirror is set.
+func newHTTPHandler(pcs PriceClients, history *HistoryStore, admin *adminAPI) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/prices/current", currentHandler(pcs))
+	mux.HandleFunc("/api/v1/prices/today", dayHandler(pcs, false))
+	mux.HandleFunc("/api/v1/prices/tomorrow", dayHandler(pcs, true))
+	mux.HandleFunc("/api/v1/prices/range", rangeHandler(pcs))
+	mux.HandleFunc("/api/v1/prices/stats", statsHandler(pcs))
+	mux.Handle("/api/v1/stream", newPriceStream(pcs))
+	if *mirrorEnabled {
+		mux.Handle("GET /api/v1/prices/{year}/{file}", newPriceMirror(pcs, BaseURL))
+	}
+	mux.HandleFunc("/api/v1/plan", planHandler(pcs))
+	mux.HandleFunc("/api/v1/battery", batteryHandler(pcs))
+	if history != nil {
+		mux.HandleFunc("/api/v1/history", historyHandler(history, pcs, "range"))
+		mux.HandleFunc("/api/v1/history/daily", historyHandler(history, pcs, "daily"))
+		mux.HandleFunc("/api/v1/history/export", historyHandler(history, pcs, "export"))
+	}
+	if admin != nil {
+		admin.register(mux)
+	}
+	return mux
+}
+
+// zoneClient returns the client for the zone query parameter, defaulting to
+// the first configured priceclass. A 404 is written for unknown zones.
+func zoneClient(w http.ResponseWriter, r *http.Request, pcs PriceClients) *PriceClient {
+	zone := r.URL.Query().Get("zone")
+	pc := pcs.Zone(zone)
+	if pc == nil {
+		http.Error(w, fmt.Sprintf("unknown zone %q", zone), http.StatusNotFound)
+	}
+	return pc
+}
+
+// planHandler serves the cheapest window for the duration, earliest, deadline
+// and profile query parameters, see parsePlanRequest.
+func planHandler(pcs PriceClients) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		pc := zoneClient(w, r, pcs)
+		if pc == nil {
+			return
+		}
+		q := r.URL.Query()
+		req, err := parsePlanRequest(q.Get("duration"), q.Get("earliest"), q.Get("deadline"), q.Get("profile"), pc.Schedule(r.Context()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plan, err := pc.FindCheapestWindow(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeJSON(w, plan)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("Writing HTTP response failed", "error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Sample is the active price interval of a zone observed at Time.
+type Sample struct {
+	Zone  string
+	Price Price
+	Time  time.Time
+	// Fields restricts the fields written by the sink, all fields if empty.
+	Fields []string
+}
+
+// Selected reports whether the field is to be written.
+func (s Sample) Selected(field string) bool {
+	return len(s.Fields) == 0 || slices.Contains(s.Fields, field)
+}
+
+// samplePoint returns the price point of the sample with the selected
+// fields, nil if no field is selected.
+func samplePoint(sample Sample, tariff *Tariff) *write.Point {
+	p := pricePoint(sample.Zone, sample.Price, tariff, sample.Time)
+	if len(sample.Fields) == 0 {
+		return p
+	}
+	selected := write.NewPointWithMeasurement(p.Name()).SetTime(p.Time())
+	for _, tag := range p.TagList() {
+		selected.AddTag(tag.Key, tag.Value)
+	}
+	for _, field := range p.FieldList() {
+		if sample.Selected(field.Key) {
+			selected.AddField(field.Key, field.Value)
+		}
+	}
+	if len(selected.FieldList()) == 0 {
+		return nil
+	}
+	return selected
+}
+
+// Sink is an output that price samples are pushed to every -influxupdaterate.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, samples []Sample) error
+}
+
+// closeSink closes the connections of the sink if it holds any.
+func closeSink(s Sink) error {
+	switch c := s.(type) {
+	case io.Closer:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
+}
+
+// intervalSink is a Sink storing whole price intervals keyed by zone and
+// start. Its Write stores the loaded schedule rather than the samples, so a
+// backfill upserts the intervals of a day instead.
+type intervalSink interface {
+	UpsertPrices(ctx context.Context, zone string, prices Prices) error
+}
+
+// currentSamples returns the active price of every zone, zones without a
+// current price are logged and skipped.
+func currentSamples(ctx context.Context, pcs PriceClients, now time.Time) []Sample {
+	var samples []Sample
+	for _, pc := range pcs {
+		price, err := pc.CurrentPrice(ctx)
+		if err != nil {
+			pc.logger().Warn("No current price", "error", err)
+			continue
+		}
+		samples = append(samples, Sample{Zone: pc.Zone(), Price: price, Time: now})
+	}
+	return samples
+}
+
+// newInfluxClient returns an InfluxDB 2 client with the default options whose
+// requests are traced.
+func newInfluxClient(addr, token string) influxdb2.Client {
+	opts := influxdb2.DefaultOptions()
+	opts.HTTPClient().Transport = otelhttp.NewTransport(opts.HTTPClient().Transport)
+	return influxdb2.NewClientWithOptions(addr, token, opts)
+}
+
+// influxSink writes samples to InfluxDB 2 timestamped at the time they were observed.
+type influxSink struct {
+	writeAPI api.WriteAPIBlocking
+	tariff   *Tariff
+}
+
+func (s *influxSink) Name() string {
+	return "influxdb"
+}
+
+func (s *influxSink) Write(ctx context.Context, samples []Sample) error {
+	points := make([]*write.Point, 0, len(samples))
+	for _, sample := range samples {
+		if p := samplePoint(sample, s.tariff); p != nil {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return s.writeAPI.WritePoint(ctx, points...)
+}
+
+// encodeLines encodes the samples as InfluxDB line protocol with timestamps
+// in the given precision.
+func encodeLines(samples []Sample, tariff *Tariff, precision time.Duration) []byte {
+	var buf bytes.Buffer
+	for _, sample := range samples {
+		if p := samplePoint(sample, tariff); p != nil {
+			buf.WriteString(write.PointToLineProtocol(p, precision))
+		}
+	}
+	return buf.Bytes()
+}
+
+// parsePrecision maps a line protocol precision name to its duration.
+func parsePrecision(precision string) (time.Duration, error) {
+	switch precision {
+	case "ns":
+		return time.Nanosecond, nil
+	case "u", "us":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	}
+	return 0, fmt.Errorf("invalid precision %q, one of: ns, us, ms, s", precision)
+}
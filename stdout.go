@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var stdoutMode = flag.Bool("stdout", false, "Write line protocol to stdout instead of InfluxDB, e.g. as a Telegraf execd input")
+var stdoutSignal = flag.String("stdout-signal", "none", "When to write to stdout, none for every -influxupdaterate or stdin for every line read from stdin (Telegraf execd signal = \"STDIN\")")
+
+// lineSink writes samples as line protocol with nanosecond timestamps.
+type lineSink struct {
+	w      io.Writer
+	tariff *Tariff
+
+	mu sync.Mutex
+}
+
+func (s *lineSink) Name() string {
+	return "stdout"
+}
+
+func (s *lineSink) Write(_ context.Context, samples []Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(encodeLines(samples, s.tariff, time.Nanosecond))
+	return err
+}
+
+// stdinSignal writes the current samples to the sink for every line read
+// from r, returning when r is closed.
+func stdinSignal(r io.Reader, pcs PriceClients, sink Sink) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		ctx, cancel := context.WithTimeout(context.Background(), *influxInterval)
+		err := sink.Write(ctx, currentSamples(ctx, pcs, clockSourceNow()))
+		cancel()
+		if err != nil {
+			slog.Error("Write failed", "sink", sink.Name(), "error", err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdinSignal(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	var out bytes.Buffer
+	sink := &lineSink{w: &out}
+
+	// Telegraf execd sends a newline for every collection interval.
+	if err := stdinSignal(strings.NewReader("\n\n"), PriceClients{loadedPriceClient(t)}, sink); err != nil {
+		t.Fatalf("stdinSignal() error got = %v, want = nil", err)
+	}
+	line := "price,currency=SEK price=0.78352 1738488600000000000\n"
+	if got, want := out.String(), line+line; got != want {
+		t.Errorf("stdout got = %q, want = %q", got, want)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamCheckInterval is how often the stream compares the loaded prices
+// with the state last sent.
+const streamCheckInterval = time.Second
+
+// streamKeepalive is how often an idle stream sends a comment to keep
+// proxies from closing it.
+const streamKeepalive = 15 * time.Second
+
+// streamBuffer is the number of events a subscriber may fall behind before
+// it is dropped.
+const streamBuffer = 64
+
+// streamEvent is a server-sent event of a zone. Type is price when the
+// active interval changes, tomorrow when tomorrow's prices are published
+// and stale when the loaded prices stop or start covering the present.
+type streamEvent struct {
+	ID   uint64
+	Type string
+	Zone string
+	Data any
+}
+
+type streamPrice struct {
+	Zone string `json:"zone"`
+	Price
+	Level string `json:"level"`
+}
+
+type streamTomorrow struct {
+	Zone   string `json:"zone"`
+	Prices Prices `json:"prices"`
+}
+
+type streamStale struct {
+	Zone     string    `json:"zone"`
+	Stale    bool      `json:"stale"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// zoneState is the state of a zone last sent to subscribers.
+type zoneState struct {
+	current   time.Time
+	published bool
+	stale     bool
+}
+
+// priceStream fans out price events to the subscribers of each zone. The
+// loaded prices are only watched while there are subscribers. The streams
+// end when the HTTP server serving them shuts down.
+type priceStream struct {
+	pcs PriceClients
+
+	mu      sync.Mutex
+	nextID  uint64
+	subs    map[chan streamEvent][]string
+	states  map[string]zoneState
+	cancel  context.CancelFunc
+	servers []*http.Server
+	closed  bool
+}
+
+func newPriceStream(pcs PriceClients) *priceStream {
+	return &priceStream{pcs: pcs, subs: map[chan streamEvent][]string{}, states: map[string]zoneState{}}
+}
+
+// events returns the events describing the zone's current state.
+func (s *priceStream) events(pc *PriceClient) []streamEvent {
+	var events []streamEvent
+	if price, err := pc.CurrentPrice(context.Background()); err == nil {
+		events = append(events, streamEvent{Type: "price", Zone: pc.Zone(), Data: streamPrice{Zone: pc.Zone(), Price: price, Level: pc.Today().Level(price)}})
+	} else {
+		events = append(events, streamEvent{Type: "stale", Zone: pc.Zone(), Data: streamStale{Zone: pc.Zone(), Stale: true, LoadedAt: pc.LoadedAt()}})
+	}
+	if schedule := pc.Schedule(context.Background()); len(schedule) > len(pc.Today()) {
+		events = append(events, streamEvent{Type: "tomorrow", Zone: pc.Zone(), Data: streamTomorrow{Zone: pc.Zone(), Prices: schedule[len(pc.Today()):]}})
+	}
+	return events
+}
+
+// state returns the zone's current state.
+func (s *priceStream) state(pc *PriceClient) zoneState {
+	var st zoneState
+	if price, err := pc.CurrentPrice(context.Background()); err == nil {
+		st.current = price.TimeStart
+	} else {
+		st.stale = true
+	}
+	st.published = len(pc.Schedule(context.Background())) > len(pc.Today())
+	return st
+}
+
+// check sends the events of zones whose state changed since the last check.
+func (s *priceStream) check() {
+	for _, pc := range s.pcs {
+		st := s.state(pc)
+		s.mu.Lock()
+		prev := s.states[pc.Zone()]
+		s.states[pc.Zone()] = st
+		s.mu.Unlock()
+
+		var events []streamEvent
+		for _, e := range s.events(pc) {
+			switch {
+			case e.Type == "price" && !st.current.Equal(prev.current):
+				events = append(events, e)
+			case e.Type == "tomorrow" && !prev.published:
+				events = append(events, e)
+			case e.Type == "stale" && !prev.stale:
+				events = append(events, e)
+			}
+		}
+		if prev.stale && !st.stale {
+			events = append(events, streamEvent{Type: "stale", Zone: pc.Zone(), Data: streamStale{Zone: pc.Zone(), LoadedAt: pc.LoadedAt()}})
+		}
+		for _, e := range events {
+			s.publish(e)
+		}
+	}
+}
+
+// publish sends the event to the subscribers of its zone. Subscribers that
+// cannot keep up are dropped.
+func (s *priceStream) publish(e streamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	e.ID = s.nextID
+	for ch, zones := range s.subs {
+		if !slices.Contains(zones, e.Zone) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("Dropping slow stream subscriber")
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// zones returns the requested zones without duplicates, all configured
+// zones if none are requested. An error is returned for unknown zones.
+func (s *priceStream) zones(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		var zones []string
+		for _, pc := range s.pcs {
+			zones = append(zones, pc.Zone())
+		}
+		return zones, nil
+	}
+	var zones []string
+	for _, zone := range requested {
+		if pc := s.pcs.Zone(zone); pc == nil || pc.Zone() != zone {
+			return nil, fmt.Errorf("unknown zone %q", zone)
+		}
+		if !slices.Contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// subscribe returns the events describing the current state of the zones,
+// which the caller sends first, and a channel with the later events. The
+// replay is returned rather than queued on the channel, so a long replay
+// can neither block the stream nor count towards streamBuffer.
+func (s *priceStream) subscribe(zones []string) (chan streamEvent, []streamEvent) {
+	var replay []streamEvent
+	var subscribed []string
+	for _, zone := range zones {
+		pc := s.pcs.Zone(zone)
+		if pc == nil || slices.Contains(subscribed, pc.Zone()) {
+			continue
+		}
+		subscribed = append(subscribed, pc.Zone())
+		replay = append(replay, s.events(pc)...)
+	}
+	ch := make(chan streamEvent, streamBuffer)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		close(ch)
+		return ch, nil
+	}
+	for i := range replay {
+		s.nextID++
+		replay[i].ID = s.nextID
+	}
+	s.subs[ch] = subscribed
+	// The replay covers the current state, later checks send the changes
+	// from it.
+	for _, pc := range s.pcs {
+		if _, ok := s.states[pc.Zone()]; !ok || s.cancel == nil {
+			s.states[pc.Zone()] = s.state(pc)
+		}
+	}
+	if s.cancel == nil {
+		var ctx context.Context
+		ctx, s.cancel = context.WithCancel(context.Background())
+		go s.watch(ctx)
+	}
+	return ch, replay
+}
+
+func (s *priceStream) unsubscribe(ch chan streamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[ch]; ok {
+		delete(s.subs, ch)
+		close(ch)
+	}
+	if len(s.subs) == 0 && s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// close ends the streams of all subscribers and of later subscriptions.
+func (s *priceStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ch := range s.subs {
+		delete(s.subs, ch)
+		close(ch)
+	}
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// closeOnShutdown closes the stream when the server of r shuts down, which
+// does not wait for the streams to end by themselves.
+func (s *priceStream) closeOnShutdown(r *http.Request) {
+	srv, ok := r.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.servers, srv) {
+		s.servers = append(s.servers, srv)
+		srv.RegisterOnShutdown(s.close)
+	}
+}
+
+func (s *priceStream) watch(ctx context.Context) {
+	ticker := time.NewTicker(streamCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// ServeHTTP streams the events of the zones in the comma separated zone
+// query parameter, all configured zones if empty, as server-sent events.
+func (s *priceStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var requested []string
+	if param := r.URL.Query().Get("zone"); param != "" {
+		requested = strings.Split(param, ",")
+	}
+	zones, err := s.zones(requested)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	s.closeOnShutdown(r)
+	ch, replay := s.subscribe(zones)
+	defer s.unsubscribe(ch)
+	for _, e := range replay {
+		writeStreamEvent(w, e)
+	}
+	flusher.Flush()
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeStreamEvent(w, e)
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent writes the event in the server-sent events format.
+func writeStreamEvent(w io.Writer, e streamEvent) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		slog.Error("Encoding stream event failed", "error", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPriceStreamCheck(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	pc := loadedPriceClient(t)
+	tomorrow := pc.tomorrow
+	pc.tomorrow = nil
+	s := newPriceStream(PriceClients{pc})
+	// Check manually instead of starting the watcher.
+	s.cancel = func() {}
+	ch, replay := s.subscribe([]string{"SE3"})
+
+	receive := func() []string {
+		var got []string
+		for _, e := range replay {
+			got = append(got, e.Type)
+		}
+		replay = nil
+		for {
+			select {
+			case e := <-ch:
+				got = append(got, e.Type)
+			default:
+				return got
+			}
+		}
+	}
+	if diff := cmp.Diff([]string{"price"}, receive()); diff != "" {
+		t.Errorf("replay mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name   string
+		update func()
+		want   []string
+	}{
+		{name: "unchanged", update: func() {}},
+		{name: "same interval", update: func() { fakec.curtime = fakec.curtime.Add(10 * time.Minute) }},
+		{name: "next interval", update: func() { fakec.curtime = fakec.curtime.Add(time.Hour) }, want: []string{"price"}},
+		{name: "tomorrow published", update: func() { pc.tomorrow = tomorrow }, want: []string{"tomorrow"}},
+		{name: "stale", update: func() { fakec.curtime = time.Date(2025, 2, 4, 10, 0, 0, 0, locale) }, want: []string{"stale"}},
+		{name: "still stale", update: func() { fakec.curtime = fakec.curtime.Add(time.Hour) }},
+		{name: "fresh", update: func() { fakec.curtime = time.Date(2025, 2, 2, 20, 30, 0, 0, locale) }, want: []string{"price", "stale"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.update()
+			s.check()
+			if diff := cmp.Diff(tt.want, receive()); diff != "" {
+				t.Errorf("check() events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	s.unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Error("channel open after unsubscribe")
+	}
+}
+
+func TestPriceStreamHandler(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{loadedPriceClient(t)}, nil, nil))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/stream?zone=SE4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status got = %d, want = %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/v1/stream?zone=SE3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type got = %q, want = text/event-stream", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 6 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	want := []string{
+		"id: 1",
+		"event: price",
+		`data: {"zone":"SE3","SEK_per_kWh":0.78352,"EUR_per_kWh":0.06814,"EXR":11.498678,"time_start":"2025-02-02T10:00:00+01:00","time_end":"2025-02-02T11:00:00+01:00","level":"normal"}`,
+		"",
+		"id: 2",
+		"event: tomorrow",
+	}
+	if diff := cmp.Diff(want, lines); diff != "" {
+		t.Errorf("stream mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPriceStreamZones(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	s := newPriceStream(PriceClients{loadedPriceClient(t)})
+	s.cancel = func() {}
+	var many []string
+	for i := 0; i < 100; i++ {
+		many = append(many, "SE3")
+	}
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "all", want: []string{"SE3"}},
+		{name: "duplicates", requested: many, want: []string{"SE3"}},
+		{name: "empty zone", requested: []string{"SE3", ""}, wantErr: true},
+		{name: "unknown zone", requested: []string{"SE4"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.zones(tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("zones() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("zones() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Duplicates passed to subscribe directly replay each zone once, and
+	// nothing is queued that could block the stream.
+	ch, replay := s.subscribe(many)
+	if len(replay) != 2 || len(ch) != 0 {
+		t.Errorf("subscribe() replay got = %d events and %d queued, want = 2 and 0", len(replay), len(ch))
+	}
+	s.unsubscribe(ch)
+}
+
+func TestPriceStreamShutdown(t *testing.T) {
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	ts := httptest.NewServer(newHTTPHandler(PriceClients{loadedPriceClient(t)}, nil, nil))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/api/v1/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	if _, err := r.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := ts.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v, want the open stream to end", err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("Shutdown() took %s", took)
+	}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Errorf("reading the rest of the stream error = %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+var tariffFile = flag.String("tariff", "", "JSON file with the tariff used to compute total_price, disabled if empty")
+
+// Date is a local calendar date in JSON form, e.g. "2025-01-01".
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, locale)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(time.DateOnly))
+}
+
+// Rate is a tariff rate that applies from ValidFrom until the next rate of
+// the same component. A rate without ValidFrom applies from the beginning.
+type Rate struct {
+	ValidFrom Date    `json:"valid_from"`
+	Value     float64 `json:"value"`
+}
+
+type Rates []Rate
+
+// At returns the rate applicable at t, or 0 if no rate applies yet.
+func (r Rates) At(t time.Time) float64 {
+	value := 0.0
+	for _, rate := range r {
+		if rate.ValidFrom.After(t) {
+			break
+		}
+		value = rate.Value
+	}
+	return value
+}
+
+// Weekday is a time.Weekday in JSON form, e.g. "mon" or "monday".
+type Weekday time.Weekday
+
+func (w *Weekday) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			*w = Weekday(d)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid weekday %q", s)
+}
+
+func (w Weekday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(time.Weekday(w).String()[:3]))
+}
+
+// TariffRule applies its rates to intervals starting within all of its
+// conditions, an empty condition matches every interval. Hours are local
+// hours in [FromHour, ToHour), where a ToHour of 0 means midnight. A window
+// with ToHour before FromHour wraps around midnight, e.g. 22 to 6, the
+// other conditions apply to the day of the interval.
+type TariffRule struct {
+	Months   []time.Month `json:"months,omitempty"`
+	Weekdays []Weekday    `json:"weekdays,omitempty"`
+	FromHour int          `json:"from_hour,omitempty"`
+	ToHour   int          `json:"to_hour,omitempty"`
+	// Holidays restricts the rule to Swedish public holidays and eves if
+	// true, or to all other days if false.
+	Holidays *bool `json:"holidays,omitempty"`
+	Value    Rates `json:"value"`
+}
+
+func (r TariffRule) matches(t time.Time) bool {
+	t = t.In(locale)
+	if len(r.Months) > 0 && !slices.Contains(r.Months, t.Month()) {
+		return false
+	}
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, Weekday(t.Weekday())) {
+		return false
+	}
+	to := r.ToHour
+	if to == 0 {
+		to = 24
+	}
+	if r.FromHour < to && (t.Hour() < r.FromHour || t.Hour() >= to) {
+		return false
+	}
+	if r.FromHour > to && t.Hour() < r.FromHour && t.Hour() >= to {
+		return false
+	}
+	if r.Holidays != nil && *r.Holidays != IsSwedishHoliday(t) {
+		return false
+	}
+	return true
+}
+
+// TariffSchedule is a time-of-use tariff where the first matching rule applies.
+type TariffSchedule []TariffRule
+
+// At returns the rate of the first rule matching t, and false if none matches.
+func (s TariffSchedule) At(t time.Time) (float64, bool) {
+	for _, rule := range s {
+		if rule.matches(t) {
+			return rule.Value.At(t), true
+		}
+	}
+	return 0, false
+}
+
+// Tariff describes the household cost on top of the spot price. All
+// components are in SEK per kWh excluding VAT, VAT is a fraction, e.g. 0.25.
+// GridFeeSchedule takes precedence over GridFee for the intervals it matches.
+type Tariff struct {
+	VAT             Rates          `json:"vat"`
+	EnergyTax       Rates          `json:"energy_tax"`
+	Markup          Rates          `json:"markup"`
+	Certificates    Rates          `json:"certificates"`
+	GridFee         Rates          `json:"grid_fee"`
+	GridFeeSchedule TariffSchedule `json:"grid_fee_schedule"`
+}
+
+// PriceBreakdown is the consumer price of an interval split into its
+// components, VAT is the amount of VAT in SEK per kWh.
+type PriceBreakdown struct {
+	Spot         float64 `json:"spot"`
+	EnergyTax    float64 `json:"energy_tax"`
+	Markup       float64 `json:"markup"`
+	Certificates float64 `json:"certificates"`
+	GridFee      float64 `json:"grid_fee"`
+	VAT          float64 `json:"vat"`
+	Total        float64 `json:"total"`
+}
+
+// loadTariff reads a tariff from a JSON file.
+func loadTariff(path string) (*Tariff, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tariff: %v", err)
+	}
+	var t Tariff
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("error parsing tariff: %v", err)
+	}
+	all := []Rates{t.VAT, t.EnergyTax, t.Markup, t.Certificates, t.GridFee}
+	for _, rule := range t.GridFeeSchedule {
+		if rule.FromHour < 0 || rule.FromHour > 23 || rule.ToHour < 0 || rule.ToHour > 23 {
+			return nil, fmt.Errorf("error parsing tariff: hours must be between 0 and 23")
+		}
+		if rule.FromHour == rule.ToHour && rule.FromHour != 0 {
+			return nil, fmt.Errorf("error parsing tariff: from_hour and to_hour %d are equal, leave both out for the whole day", rule.FromHour)
+		}
+		all = append(all, rule.Value)
+	}
+	for _, rates := range all {
+		sort.SliceStable(rates, func(i, j int) bool { return rates[i].ValidFrom.Before(rates[j].ValidFrom.Time) })
+	}
+	return &t, nil
+}
+
+// Breakdown computes the consumer price for the interval using the rates
+// that applied at its start.
+func (t *Tariff) Breakdown(p Price) PriceBreakdown {
+	at := p.TimeStart
+	b := PriceBreakdown{
+		Spot:         p.SEKPerkWh,
+		EnergyTax:    t.EnergyTax.At(at),
+		Markup:       t.Markup.At(at),
+		Certificates: t.Certificates.At(at),
+		GridFee:      t.GridFee.At(at),
+	}
+	if fee, ok := t.GridFeeSchedule.At(at); ok {
+		b.GridFee = fee
+	}
+	net := b.Spot + b.EnergyTax + b.Markup + b.Certificates + b.GridFee
+	b.VAT = net * t.VAT.At(at)
+	b.Total = net + b.VAT
+	return b
+}
+
+// pricePoint builds the InfluxDB point for a price interval, with the
+// tariff components added if a tariff is configured and the zone tagged as
+// priceclass if -priceclass-tag is set.
+func pricePoint(zone string, price Price, tariff *Tariff, ts time.Time) *write.Point {
+	p := influxdb2.NewPointWithMeasurement("price").
+		AddTag("currency", "SEK").
+		AddField("price", price.SEKPerkWh).
+		SetTime(ts)
+	if *priceClassTag {
+		p.AddTag("priceclass", zone)
+	}
+	if tariff != nil {
+		b := tariff.Breakdown(price)
+		p.AddField("total_price", b.Total).
+			AddField("energy_tax", b.EnergyTax).
+			AddField("markup", b.Markup).
+			AddField("certificates", b.Certificates).
+			AddField("grid_fee", b.GridFee).
+			AddField("vat", b.VAT)
+	}
+	return p
+}
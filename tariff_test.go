@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+func TestTariffBreakdown(t *testing.T) {
+	loc, err := time.LoadLocation(Locale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tariff, err := loadTariff("tariff.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		price Price
+		want  PriceBreakdown
+	}{
+		{
+			name:  "2025 rates",
+			price: Price{SEKPerkWh: 0.37003, TimeStart: time.Date(2025, 2, 2, 0, 0, 0, 0, loc)},
+			want: PriceBreakdown{
+				Spot: 0.37003, EnergyTax: 0.439, Markup: 0.05, Certificates: 0.01, GridFee: 0.25,
+				VAT: 1.11903 * 0.25, Total: 1.11903 * 1.25,
+			},
+		},
+		{
+			name:  "historical rates",
+			price: Price{SEKPerkWh: 1, TimeStart: time.Date(2024, 12, 31, 23, 0, 0, 0, loc)},
+			want: PriceBreakdown{
+				Spot: 1, EnergyTax: 0.428, Markup: 0.05, Certificates: 0.01, GridFee: 0.25,
+				VAT: 1.738 * 0.25, Total: 1.738 * 1.25,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tariff.Breakdown(tc.price), cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+				t.Errorf("Breakdown() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTariffGridFeeSchedule(t *testing.T) {
+	tariff, err := loadTariff("tariff.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"winter weekday high load", time.Date(2025, 2, 3, 6, 0, 0, 0, locale), 0.7},
+		{"winter weekday last high load hour", time.Date(2025, 2, 3, 21, 0, 0, 0, locale), 0.7},
+		{"winter weekday night", time.Date(2025, 2, 3, 22, 0, 0, 0, locale), 0.25},
+		{"winter weekend", time.Date(2025, 2, 2, 12, 0, 0, 0, locale), 0.25},
+		{"winter holiday", time.Date(2025, 1, 6, 12, 0, 0, 0, locale), 0.25},
+		{"summer weekday", time.Date(2025, 6, 3, 12, 0, 0, 0, locale), 0.25},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tariff.Breakdown(Price{TimeStart: tc.at, TimeEnd: tc.at.Add(time.Hour)}).GridFee
+			if got != tc.want {
+				t.Errorf("Breakdown() grid fee got = %v, want = %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTariffOvernightRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tariff.json")
+	err := os.WriteFile(path, []byte(`{"grid_fee":[{"value":0.5}],"grid_fee_schedule":[{"weekdays":["mon"],"from_hour":22,"to_hour":6,"value":[{"value":0.1}]}]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tariff, err := loadTariff(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"monday late evening", time.Date(2025, 2, 3, 22, 0, 0, 0, locale), 0.1},
+		{"monday early morning", time.Date(2025, 2, 3, 5, 0, 0, 0, locale), 0.1},
+		{"monday midnight", time.Date(2025, 2, 3, 0, 0, 0, 0, locale), 0.1},
+		{"monday end of window", time.Date(2025, 2, 3, 6, 0, 0, 0, locale), 0.5},
+		{"monday day", time.Date(2025, 2, 3, 12, 0, 0, 0, locale), 0.5},
+		{"tuesday early morning", time.Date(2025, 2, 4, 1, 0, 0, 0, locale), 0.5},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tariff.Breakdown(Price{TimeStart: tc.at, TimeEnd: tc.at.Add(time.Hour)}).GridFee
+			if got != tc.want {
+				t.Errorf("Breakdown() grid fee got = %v, want = %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadTariffInvalidHours(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"hour out of range", `{"from_hour":24,"value":[]}`},
+		{"equal hours", `{"from_hour":6,"to_hour":6,"value":[]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tariff.json")
+			if err := os.WriteFile(path, []byte(`{"grid_fee_schedule":[`+tc.rule+`]}`), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadTariff(path); err == nil {
+				t.Errorf("loadTariff() error got = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoadTariffUnsorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tariff.json")
+	err := os.WriteFile(path, []byte(`{"energy_tax":[{"valid_from":"2025-01-01","value":2},{"valid_from":"2024-01-01","value":1}]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tariff, err := loadTariff(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tariff.EnergyTax.At(time.Date(2024, 6, 1, 0, 0, 0, 0, locale)); got != 1 {
+		t.Errorf("At() got = %v, want = 1", got)
+	}
+	if got := tariff.EnergyTax.At(time.Date(2023, 6, 1, 0, 0, 0, 0, locale)); got != 0 {
+		t.Errorf("At() got = %v, want = 0", got)
+	}
+}
+
+func TestPricePoint(t *testing.T) {
+	ts := time.Unix(1738450800, 0)
+	price := Price{SEKPerkWh: 1, TimeStart: ts}
+
+	got := write.PointToLineProtocol(pricePoint("SE3", price, nil, ts), time.Second)
+	if want := "price,currency=SEK price=1 1738450800\n"; got != want {
+		t.Errorf("pricePoint() got = %q, want = %q", got, want)
+	}
+
+	defer func(tag bool) { *priceClassTag = tag }(*priceClassTag)
+	*priceClassTag = true
+	got = write.PointToLineProtocol(pricePoint("SE3", price, nil, ts), time.Second)
+	if want := "price,currency=SEK,priceclass=SE3 price=1 1738450800\n"; got != want {
+		t.Errorf("pricePoint() with priceclass tag got = %q, want = %q", got, want)
+	}
+
+	tariff := &Tariff{VAT: Rates{{Value: 0.25}}, GridFee: Rates{{Value: 0.2}}}
+	got = write.PointToLineProtocol(pricePoint("SE3", price, tariff, ts), time.Second)
+	for _, field := range []string{"total_price=1.5", "grid_fee=0.2", "vat=0.3", "energy_tax=0"} {
+		if !strings.Contains(got, field) {
+			t.Errorf("pricePoint() got = %q, want field %s", got, field)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var otlpEndpoint = flag.String("otlp-endpoint", "", "OTLP gRPC endpoint to export traces to, e.g. localhost:4317, tracing disabled if empty")
+var otlpInsecure = flag.Bool("otlp-insecure", false, "Export traces to -otlp-endpoint without TLS")
+
+// tracer creates the spans of price2influx. Until setupTracing is called the
+// spans are not recorded.
+var tracer = otel.Tracer("price2influx")
+
+// Span attributes of the fetch and write paths.
+var (
+	zoneKey     = attribute.Key("price2influx.zone")
+	dateKey     = attribute.Key("price2influx.date")
+	providerKey = attribute.Key("price2influx.provider")
+	sinkKey     = attribute.Key("price2influx.sink")
+	samplesKey  = attribute.Key("price2influx.samples")
+)
+
+// setupTracing exports the spans to -otlp-endpoint and propagates the trace
+// context on outgoing requests. The returned function flushes and stops the
+// export.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(*otlpEndpoint)}
+	if *otlpInsecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName("price2influx")))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// dateAttr returns the local day of date as a span attribute.
+func dateAttr(date time.Time) attribute.KeyValue {
+	return dateKey.String(date.In(locale).Format(time.DateOnly))
+}
+
+// endSpan records err on the span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
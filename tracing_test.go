@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	spanRecorderOnce sync.Once
+	spanRecorder     *tracetest.SpanRecorder
+)
+
+// recordSpans records the spans of the global tracer provider, which can
+// only be set once per process.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	spanRecorderOnce.Do(func() {
+		spanRecorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spanRecorder.Reset()
+	return spanRecorder
+}
+
+// endedSpans returns the ended spans by name.
+func endedSpans(rec *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range rec.Ended() {
+		spans[s.Name()] = s
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	rec := recordSpans(t)
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		fmt.Fprintln(w, day1)
+	}))
+	defer upstream.Close()
+	pc := &PriceClient{
+		priceClass: "SE3",
+		baseURL:    upstream.URL,
+		client:     &http.Client{Transport: otelhttp.NewTransport(upstream.Client().Transport)},
+	}
+
+	if err := pc.LoadPrices(); err != nil {
+		t.Fatalf("LoadPrices() error = %v", err)
+	}
+	spans := endedSpans(rec)
+	for _, name := range []string{"LoadPrices", "fetchPrices", "parsePrices", "PriceClient.lock", "HTTP GET"} {
+		if _, ok := spans[name]; !ok {
+			t.Errorf("span %s missing, got %v", name, slices.Sorted(maps.Keys(spans)))
+		}
+	}
+	load := spans["LoadPrices"]
+	for _, want := range []attribute.KeyValue{zoneKey.String("SE3"), dateKey.String("2025-02-02"), providerKey.String(Provider)} {
+		if !slices.Contains(load.Attributes(), want) {
+			t.Errorf("LoadPrices attributes got = %v, want %v", load.Attributes(), want)
+		}
+	}
+	if fetch := spans["fetchPrices"]; fetch.Parent().SpanID() != load.SpanContext().SpanID() {
+		t.Errorf("fetchPrices parent got = %v, want LoadPrices", fetch.Parent().SpanID())
+	}
+	if want := load.SpanContext().TraceID().String(); len(traceparent) < 35 || traceparent[3:35] != want {
+		t.Errorf("traceparent got = %q, want trace %s", traceparent, want)
+	}
+
+	rec.Reset()
+	route := newSinkRoute(&recordSink{err: errors.New("unavailable")})
+	route.write([]Sample{{Zone: "SE3", Price: Price{SEKPerkWh: 1}, Time: fakec.curtime}})
+	spans = endedSpans(rec)
+	write, ok := spans["sink.Write"]
+	if !ok {
+		t.Fatalf("sink.Write span missing")
+	}
+	if write.Parent().SpanID() != spans["sinkRoute.write"].SpanContext().SpanID() {
+		t.Errorf("sink.Write parent got = %v, want sinkRoute.write", write.Parent().SpanID())
+	}
+	if !slices.Contains(write.Attributes(), sinkKey.String("record")) || write.Status().Description != "unavailable" {
+		t.Errorf("sink.Write got attributes = %v, status = %v", write.Attributes(), write.Status())
+	}
+	if _, ok := spans["filterSamples"]; !ok {
+		t.Errorf("filterSamples span missing")
+	}
+}
+
+func TestTracedHandler(t *testing.T) {
+	rec := recordSpans(t)
+	h := tracedHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/api/v1/prices/current", want: 1},
+		{path: "/metrics", want: 0},
+		{path: "/healthz", want: 0},
+		{path: "/readyz", want: 0},
+		{path: "/status", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec.Reset()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got := len(rec.Ended()); got != tt.want {
+				t.Errorf("spans got = %d, want = %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkRouteRunTracing(t *testing.T) {
+	rec := recordSpans(t)
+	fakec := fakeclock{
+		curtime: time.Date(2025, 2, 2, 10, 30, 0, 0, locale),
+	}
+	clockSourceNow = fakec.Now
+	pc := loadedPriceClient(t)
+	sink := &recordSink{}
+	route := &sinkRoute{name: "record", sink: sink, interval: 5 * time.Millisecond, timeout: time.Second, onFailure: "drop"}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		route.run(ctx, func(ctx context.Context) []Sample {
+			return currentSamples(ctx, PriceClients{pc}, fakec.curtime)
+		})
+	}()
+	deadline := time.After(5 * time.Second)
+	for sink.count() < 1 {
+		select {
+		case <-deadline:
+			t.Fatal("no write within 5s")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	var write sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == "sinkRoute.write" {
+			write = s
+			break
+		}
+	}
+	if write == nil {
+		t.Fatalf("sinkRoute.write span missing")
+	}
+	var lock bool
+	for _, s := range rec.Ended() {
+		if s.Name() == "PriceClient.lock" && s.Parent().SpanID() == write.SpanContext().SpanID() {
+			lock = true
+		}
+	}
+	if !lock {
+		t.Errorf("PriceClient.lock span of the source missing in sinkRoute.write")
+	}
+}
+
+func TestSinkWriteTracing(t *testing.T) {
+	recordSpans(t)
+	var mu sync.Mutex
+	traceparents := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents[r.URL.Path] = r.Header.Get("traceparent")
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	v1, err := newInfluxV1Sink(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3, err := newInfluxV3Sink(ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newInfluxClient(ts.URL, "token")
+	defer client.Close()
+
+	tests := []struct {
+		name string
+		sink Sink
+		path string
+	}{
+		{name: "influxdb", sink: &influxSink{writeAPI: client.WriteAPIBlocking("org", "bucket")}, path: "/api/v2/write"},
+		{name: "influxdb-v1", sink: v1, path: "/write"},
+		{name: "influxdb-v3", sink: v3, path: "/api/v3/write_lp"},
+		{name: "remotewrite", sink: newRemoteWriteSink(ts.URL+"/api/v1/write", nil), path: "/api/v1/write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, span := tracer.Start(context.Background(), "write")
+			samples := []Sample{{Zone: "SE3", Price: Price{SEKPerkWh: 1, TimeStart: time.Now(), TimeEnd: time.Now().Add(time.Hour)}, Time: time.Now()}}
+			if err := tt.sink.Write(ctx, samples); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			span.End()
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := traceparents[tt.path], span.SpanContext().TraceID().String(); !strings.Contains(got, want) {
+				t.Errorf("traceparent got = %q, want trace %s", got, want)
+			}
+		})
+	}
+}